@@ -63,5 +63,19 @@ Note that the xevent package's X event loop is not concurrent. Namely,
 designing a generally concurrent X event loop is extremely complex. Instead,
 the onus is on you, the user, to design concurrent callback functions if
 concurrency is desired.
+
+Testing
+
+NewMock creates an XUtil connected to a fake X server that runs in memory, so
+code using xgbutil can be tested without a real X server. The fake server
+keeps atoms and window properties, records every request, and can send
+events:
+
+	X, mock, err := xgbutil.NewMock()
+	if err != nil {
+		// handle error
+	}
+	ewmh.WmNameSet(X, 0x1, "test")
+	mock.SendEvent(xproto.ConfigureNotifyEvent{Event: 0x1, Window: 0x1})
 */
 package xgbutil
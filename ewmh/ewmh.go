@@ -1,6 +1,8 @@
 package ewmh
 
 import (
+	"fmt"
+
 	"github.com/jezek/xgb/xproto"
 
 	"github.com/jezek/xgbutil"
@@ -34,6 +36,41 @@ func ActiveWindowGet(xu *xgbutil.XUtil) (xproto.Window, error) {
 		"_NET_ACTIVE_WINDOW"))
 }
 
+// ActiveWindowWatch runs 'f' with the new active window every time the
+// _NET_ACTIVE_WINDOW property on the root window changes. If the property is
+// deleted (or can't be read), 'f' is run with 0.
+// PropertyChange events are added to the root window's event mask, and 'f'
+// is run from inside the main event loop.
+func ActiveWindowWatch(xu *xgbutil.XUtil, f func(win xproto.Window)) error {
+	activeAtom, err := xprop.Atm(xu, "_NET_ACTIVE_WINDOW")
+	if err != nil {
+		return err
+	}
+
+	err = xevent.SelectInputAdd(xu, xu.RootWin(),
+		xproto.EventMaskPropertyChange)
+	if err != nil {
+		return err
+	}
+
+	xevent.PropertyNotifyFun(
+		func(xu *xgbutil.XUtil, ev xevent.PropertyNotifyEvent) {
+			if ev.Atom != activeAtom {
+				return
+			}
+			if ev.State == xproto.PropertyDelete {
+				f(0)
+				return
+			}
+			active, err := ActiveWindowGet(xu)
+			if err != nil {
+				active = 0
+			}
+			f(active)
+		}).Connect(xu, xu.RootWin())
+	return nil
+}
+
 // _NET_ACTIVE_WINDOW set
 func ActiveWindowSet(xu *xgbutil.XUtil, win xproto.Window) error {
 	return xprop.ChangeProp32(xu, xu.RootWin(), "_NET_ACTIVE_WINDOW", "WINDOW",
@@ -71,6 +108,69 @@ func ClientListStackingGet(xu *xgbutil.XUtil) ([]xproto.Window, error) {
 		"_NET_CLIENT_LIST_STACKING"))
 }
 
+// ClientListStackingWatch runs 'f' with the new stacking order every time the
+// _NET_CLIENT_LIST_STACKING property on the root window changes. If the
+// property is deleted (or can't be read), 'f' is run with nil.
+// The list is also cached with the connection, so that
+// ClientListStackingCached doesn't need to ask the X server for it until it
+// changes again.
+// PropertyChange events are added to the root window's event mask, and 'f'
+// is run from inside the main event loop.
+func ClientListStackingWatch(xu *xgbutil.XUtil,
+	f func(wins []xproto.Window)) error {
+
+	wins, err := ClientListStackingGet(xu)
+	if err != nil {
+		wins = nil
+	}
+	stackingCacheSet(xu, wins)
+
+	return xprop.Watch(xu, xu.RootWin(), "_NET_CLIENT_LIST_STACKING",
+		func(xu *xgbutil.XUtil, reply *xproto.GetPropertyReply) {
+			var wins []xproto.Window
+			if reply != nil {
+				wins, _ = xprop.PropValWindows(reply, nil)
+			}
+			stackingCacheSet(xu, wins)
+			f(wins)
+		})
+}
+
+// ClientListStackingCached is just like ClientListStackingGet, except that
+// once ClientListStackingWatch has been called, the list is returned from a
+// cache that is updated when the property changes, instead of asking the X
+// server for it every time.
+func ClientListStackingCached(xu *xgbutil.XUtil) ([]xproto.Window, error) {
+	xu.StackingCacheLck.RLock()
+	wins, ok := xu.StackingCache, xu.StackingCached
+	xu.StackingCacheLck.RUnlock()
+
+	if !ok {
+		return ClientListStackingGet(xu)
+	}
+	if wins == nil {
+		return nil, fmt.Errorf("ClientListStackingCached: " +
+			"_NET_CLIENT_LIST_STACKING is not set on the root window.")
+	}
+	cpy := make([]xproto.Window, len(wins))
+	copy(cpy, wins)
+	return cpy, nil
+}
+
+// stackingCacheSet updates the cached stacking order for 'xu'.
+func stackingCacheSet(xu *xgbutil.XUtil, wins []xproto.Window) {
+	xu.StackingCacheLck.Lock()
+	defer xu.StackingCacheLck.Unlock()
+
+	xu.StackingCached = true
+	if wins == nil {
+		xu.StackingCache = nil
+		return
+	}
+	xu.StackingCache = make([]xproto.Window, len(wins))
+	copy(xu.StackingCache, wins)
+}
+
 // _NET_CLIENT_LIST_STACKING set
 func ClientListStackingSet(xu *xgbutil.XUtil, wins []xproto.Window) error {
 	return xprop.ChangeProp32(xu, xu.RootWin(), "_NET_CLIENT_LIST_STACKING",
@@ -116,7 +216,7 @@ func CurrentDesktopReqExtra(xu *xgbutil.XUtil, desk int,
 
 // _NET_DESKTOP_NAMES get
 func DesktopNamesGet(xu *xgbutil.XUtil) ([]string, error) {
-	return xprop.PropValStrs(xprop.GetProperty(xu, xu.RootWin(),
+	return xprop.PropValUTF8s(xprop.GetProperty(xu, xu.RootWin(),
 		"_NET_DESKTOP_NAMES"))
 }
 
@@ -145,6 +245,10 @@ func DesktopGeometryGet(xu *xgbutil.XUtil) (*DesktopGeometry, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(geom) < 2 {
+		return nil, fmt.Errorf("DesktopGeometryGet: Expected two values for "+
+			"_NET_DESKTOP_GEOMETRY, but got %d.", len(geom))
+	}
 
 	return &DesktopGeometry{Width: int(geom[0]), Height: int(geom[1])}, nil
 }
@@ -438,6 +542,25 @@ func SupportedSet(xu *xgbutil.XUtil, atomNames []string) error {
 		atoms...)
 }
 
+// IsSupported reports whether the window manager lists 'atomName' (i.e.,
+// "_NET_WM_STATE_FULLSCREEN") in _NET_SUPPORTED. If _NET_SUPPORTED can't be
+// read, which usually means that no EWMH compliant window manager is
+// running, false is returned.
+// _NET_SUPPORTED is read every time, so use SupportedGet when checking many
+// atoms at once.
+func IsSupported(xu *xgbutil.XUtil, atomName string) bool {
+	supported, err := SupportedGet(xu)
+	if err != nil {
+		return false
+	}
+	for _, name := range supported {
+		if name == atomName {
+			return true
+		}
+	}
+	return false
+}
+
 // _NET_SUPPORTING_WM_CHECK get
 func SupportingWmCheckGet(xu *xgbutil.XUtil,
 	win xproto.Window) (xproto.Window, error) {
@@ -503,6 +626,29 @@ func WmAllowedActionsSet(xu *xgbutil.XUtil, win xproto.Window,
 		atoms...)
 }
 
+// _NET_WM_BYPASS_COMPOSITOR constants for the compositing hint.
+const (
+	BypassCompositorNoPreference = iota
+	BypassCompositorDisable
+	BypassCompositorNever
+)
+
+// _NET_WM_BYPASS_COMPOSITOR get
+func WmBypassCompositorGet(xu *xgbutil.XUtil,
+	win xproto.Window) (uint, error) {
+
+	return xprop.PropValNum(xprop.GetProperty(xu, win,
+		"_NET_WM_BYPASS_COMPOSITOR"))
+}
+
+// _NET_WM_BYPASS_COMPOSITOR set
+func WmBypassCompositorSet(xu *xgbutil.XUtil, win xproto.Window,
+	mode uint) error {
+
+	return xprop.ChangeProp32(xu, win, "_NET_WM_BYPASS_COMPOSITOR",
+		"CARDINAL", mode)
+}
+
 // _NET_WM_DESKTOP get
 func WmDesktopGet(xu *xgbutil.XUtil, win xproto.Window) (uint, error) {
 	return xprop.PropValNum(xprop.GetProperty(xu, win, "_NET_WM_DESKTOP"))
@@ -545,6 +691,10 @@ func WmFullscreenMonitorsGet(xu *xgbutil.XUtil,
 	if err != nil {
 		return nil, err
 	}
+	if len(raw) < 4 {
+		return nil, fmt.Errorf("WmFullscreenMonitorsGet: Expected 4 "+
+			"monitor indices but got %d.", len(raw))
+	}
 
 	return &WmFullscreenMonitors{
 		Top:    raw[0],
@@ -629,8 +779,16 @@ func WmIconGet(xu *xgbutil.XUtil, win xproto.Window) ([]WmIcon, error) {
 	wmicons := make([]WmIcon, 0)
 	start := uint(0)
 	for int(start) < len(icon) {
+		if int(start)+2 > len(icon) {
+			return nil, fmt.Errorf("WmIconGet: Truncated icon header at "+
+				"offset %d in _NET_WM_ICON.", start)
+		}
 		w, h := icon[start], icon[start+1]
 		upto := w * h
+		if upto > uint(len(icon))-start-2 {
+			return nil, fmt.Errorf("WmIconGet: Icon of size %dx%d at offset "+
+				"%d doesn't fit in _NET_WM_ICON.", w, h, start)
+		}
 
 		wmicon := WmIcon{
 			Width:  w,
@@ -699,7 +857,7 @@ func WmIconGeometrySet(xu *xgbutil.XUtil, win xproto.Window,
 
 // _NET_WM_ICON_NAME get
 func WmIconNameGet(xu *xgbutil.XUtil, win xproto.Window) (string, error) {
-	return xprop.PropValStr(xprop.GetProperty(xu, win, "_NET_WM_ICON_NAME"))
+	return xprop.PropValUTF8(xprop.GetProperty(xu, win, "_NET_WM_ICON_NAME"))
 }
 
 // _NET_WM_ICON_NAME set
@@ -740,7 +898,7 @@ func WmMoveresizeExtra(xu *xgbutil.XUtil, win xproto.Window, direction,
 
 // _NET_WM_NAME get
 func WmNameGet(xu *xgbutil.XUtil, win xproto.Window) (string, error) {
-	return xprop.PropValStr(xprop.GetProperty(xu, win, "_NET_WM_NAME"))
+	return xprop.PropValUTF8(xprop.GetProperty(xu, win, "_NET_WM_NAME"))
 }
 
 // _NET_WM_NAME set
@@ -782,9 +940,13 @@ func WmOpaqueRegionGet(xu *xgbutil.XUtil,
 }
 
 // _NET_WM_OPAQUE_REGION set
+// If 'regions' is empty, the property is deleted.
 func WmOpaqueRegionSet(xu *xgbutil.XUtil, win xproto.Window,
 	regions []WmOpaqueRegion) error {
 
+	if len(regions) == 0 {
+		return xprop.DeleteProp(xu, win, "_NET_WM_OPAQUE_REGION")
+	}
 	raw := make([]uint, len(regions)*4)
 
 	for i, region := range regions {
@@ -841,6 +1003,23 @@ const (
 	StateToggle
 )
 
+// _NET_WM_STATE atom names for each of the states in the EWMH spec.
+const (
+	WmStateModal            = "_NET_WM_STATE_MODAL"
+	WmStateSticky           = "_NET_WM_STATE_STICKY"
+	WmStateMaximizedVert    = "_NET_WM_STATE_MAXIMIZED_VERT"
+	WmStateMaximizedHorz    = "_NET_WM_STATE_MAXIMIZED_HORZ"
+	WmStateShaded           = "_NET_WM_STATE_SHADED"
+	WmStateSkipTaskbar      = "_NET_WM_STATE_SKIP_TASKBAR"
+	WmStateSkipPager        = "_NET_WM_STATE_SKIP_PAGER"
+	WmStateHidden           = "_NET_WM_STATE_HIDDEN"
+	WmStateFullscreen       = "_NET_WM_STATE_FULLSCREEN"
+	WmStateAbove            = "_NET_WM_STATE_ABOVE"
+	WmStateBelow            = "_NET_WM_STATE_BELOW"
+	WmStateDemandsAttention = "_NET_WM_STATE_DEMANDS_ATTENTION"
+	WmStateFocused          = "_NET_WM_STATE_FOCUSED"
+)
+
 // _NET_WM_STATE get
 func WmStateGet(xu *xgbutil.XUtil, win xproto.Window) ([]string, error) {
 	raw, err := xprop.GetProperty(xu, win, "_NET_WM_STATE")
@@ -890,6 +1069,23 @@ func WmStateReqExtra(xu *xgbutil.XUtil, win xproto.Window, action int,
 		source)
 }
 
+// WmStateAdd is a shortcut for WmStateReq with the StateAdd action.
+func WmStateAdd(xu *xgbutil.XUtil, win xproto.Window, state string) error {
+	return WmStateReq(xu, win, StateAdd, state)
+}
+
+// WmStateRemove is a shortcut for WmStateReq with the StateRemove action.
+func WmStateRemove(xu *xgbutil.XUtil, win xproto.Window, state string) error {
+	return WmStateReq(xu, win, StateRemove, state)
+}
+
+// WmStateToggle is a shortcut for WmStateReq with the StateToggle action.
+// Use WmStateReqExtra to toggle two states at once. (i.e., both
+// WmStateMaximizedVert and WmStateMaximizedHorz.)
+func WmStateToggle(xu *xgbutil.XUtil, win xproto.Window, state string) error {
+	return WmStateReq(xu, win, StateToggle, state)
+}
+
 // WmStrut struct organizes information for the _NET_WM_STRUT property.
 // Namely, it encapsulates its four values: left, right, top and bottom.
 type WmStrut struct {
@@ -1048,7 +1244,7 @@ func WmUserTimeWindowSet(xu *xgbutil.XUtil, win xproto.Window,
 func WmVisibleIconNameGet(xu *xgbutil.XUtil,
 	win xproto.Window) (string, error) {
 
-	return xprop.PropValStr(xprop.GetProperty(xu, win,
+	return xprop.PropValUTF8(xprop.GetProperty(xu, win,
 		"_NET_WM_VISIBLE_ICON_NAME"))
 }
 
@@ -1062,7 +1258,7 @@ func WmVisibleIconNameSet(xu *xgbutil.XUtil, win xproto.Window,
 
 // _NET_WM_VISIBLE_NAME get
 func WmVisibleNameGet(xu *xgbutil.XUtil, win xproto.Window) (string, error) {
-	return xprop.PropValStr(xprop.GetProperty(xu, win, "_NET_WM_VISIBLE_NAME"))
+	return xprop.PropValUTF8(xprop.GetProperty(xu, win, "_NET_WM_VISIBLE_NAME"))
 }
 
 // _NET_WM_VISIBLE_NAME set
@@ -1089,13 +1285,38 @@ func WmWindowOpacityGet(xu *xgbutil.XUtil, win xproto.Window) (float64, error) {
 }
 
 // _NET_WM_WINDOW_OPACITY set
+// The opacity is clamped to the range [0.0, 1.0].
 func WmWindowOpacitySet(xu *xgbutil.XUtil, win xproto.Window,
 	opacity float64) error {
 
+	if opacity < 0 {
+		opacity = 0
+	} else if opacity > 1 {
+		opacity = 1
+	}
 	return xprop.ChangeProp32(xu, win, "_NET_WM_WINDOW_OPACITY", "CARDINAL",
 		uint(opacity*0xffffffff))
 }
 
+// _NET_WM_WINDOW_TYPE atom names for each of the window types in the EWMH
+// spec.
+const (
+	WmWindowTypeDesktop      = "_NET_WM_WINDOW_TYPE_DESKTOP"
+	WmWindowTypeDock         = "_NET_WM_WINDOW_TYPE_DOCK"
+	WmWindowTypeToolbar      = "_NET_WM_WINDOW_TYPE_TOOLBAR"
+	WmWindowTypeMenu         = "_NET_WM_WINDOW_TYPE_MENU"
+	WmWindowTypeUtility      = "_NET_WM_WINDOW_TYPE_UTILITY"
+	WmWindowTypeSplash       = "_NET_WM_WINDOW_TYPE_SPLASH"
+	WmWindowTypeDialog       = "_NET_WM_WINDOW_TYPE_DIALOG"
+	WmWindowTypeDropdownMenu = "_NET_WM_WINDOW_TYPE_DROPDOWN_MENU"
+	WmWindowTypePopupMenu    = "_NET_WM_WINDOW_TYPE_POPUP_MENU"
+	WmWindowTypeTooltip      = "_NET_WM_WINDOW_TYPE_TOOLTIP"
+	WmWindowTypeNotification = "_NET_WM_WINDOW_TYPE_NOTIFICATION"
+	WmWindowTypeCombo        = "_NET_WM_WINDOW_TYPE_COMBO"
+	WmWindowTypeDnd          = "_NET_WM_WINDOW_TYPE_DND"
+	WmWindowTypeNormal       = "_NET_WM_WINDOW_TYPE_NORMAL"
+)
+
 // _NET_WM_WINDOW_TYPE get
 func WmWindowTypeGet(xu *xgbutil.XUtil, win xproto.Window) ([]string, error) {
 	raw, err := xprop.GetProperty(xu, win, "_NET_WM_WINDOW_TYPE")
@@ -1114,6 +1335,26 @@ func WmWindowTypeSet(xu *xgbutil.XUtil, win xproto.Window,
 	return xprop.ChangeProp32(xu, win, "_NET_WM_WINDOW_TYPE", "ATOM", atoms...)
 }
 
+// IsWindowType reports whether 'windowType' (i.e., WmWindowTypeDialog) is one
+// of the types in the _NET_WM_WINDOW_TYPE property of 'win'.
+// Note that the EWMH says that a window without the property should be
+// treated as WmWindowTypeNormal (or WmWindowTypeDialog if it is transient),
+// but IsWindowType returns the error from WmWindowTypeGet in that case.
+func IsWindowType(xu *xgbutil.XUtil, win xproto.Window,
+	windowType string) (bool, error) {
+
+	types, err := WmWindowTypeGet(xu, win)
+	if err != nil {
+		return false, err
+	}
+	for _, t := range types {
+		if t == windowType {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // Workarea is a struct that represents a rectangle as a bounding box of
 // a single desktop. So there should be as many Workarea structs as there
 // are desktops.
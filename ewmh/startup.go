@@ -0,0 +1,101 @@
+package ewmh
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xevent"
+	"github.com/jezek/xgbutil/xprop"
+)
+
+// StartupInfoSet sends a "new:" startup notification message for the launch
+// sequence identified by 'id'. 'props' contains any other keys of the
+// message. (i.e., "NAME", "SCREEN", "BIN" or "WMCLASS".)
+// See the startup notification specification for the meaning of each key:
+// http://standards.freedesktop.org/startup-notification-spec/
+func StartupInfoSet(xu *xgbutil.XUtil, id string,
+	props map[string]string) error {
+
+	return StartupInfoSend(xu, startupInfoMessage("new", id, props))
+}
+
+// StartupInfoRemove sends a "remove:" startup notification message, which
+// indicates that the launch sequence identified by 'id' has completed.
+func StartupInfoRemove(xu *xgbutil.XUtil, id string) error {
+	return StartupInfoSend(xu, startupInfoMessage("remove", id, nil))
+}
+
+// StartupInfoSend sends a raw startup notification message to the root
+// window. The message is split into client messages of 20 bytes each. The
+// first is of type _NET_STARTUP_INFO_BEGIN and the rest are of type
+// _NET_STARTUP_INFO. The message is terminated by a nul byte.
+func StartupInfoSend(xu *xgbutil.XUtil, message string) error {
+	beginAtom, err := xprop.Atm(xu, "_NET_STARTUP_INFO_BEGIN")
+	if err != nil {
+		return err
+	}
+	infoAtom, err := xprop.Atm(xu, "_NET_STARTUP_INFO")
+	if err != nil {
+		return err
+	}
+
+	// Unused bytes in the last client message are zero, but the terminating
+	// nul must be sent even if the message is a multiple of 20 bytes.
+	buf := append([]byte(message), 0)
+	typ := beginAtom
+	for len(buf) > 0 {
+		n := 20
+		if len(buf) < n {
+			n = len(buf)
+		}
+		data := make([]interface{}, n)
+		for i, b := range buf[:n] {
+			data[i] = b
+		}
+		buf = buf[n:]
+
+		cm, err := xevent.NewClientMessage(8, xu.Dummy(), typ, data...)
+		if err != nil {
+			return err
+		}
+		err = xevent.SendRootEvent(xu, cm,
+			uint32(xproto.EventMaskPropertyChange))
+		if err != nil {
+			return err
+		}
+		typ = infoAtom
+	}
+	return nil
+}
+
+// startupInfoMessage builds a startup notification message of the given
+// type. The ID key always comes first, and the rest are sorted by key.
+func startupInfoMessage(typ, id string, props map[string]string) string {
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		if key != "ID" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	msg := typ + ": ID=" + startupInfoQuote(id)
+	for _, key := range keys {
+		msg += " " + key + "=" + startupInfoQuote(props[key])
+	}
+	return msg
+}
+
+// startupInfoQuote quotes a startup notification value if it contains any
+// spaces, double quotes or backslashes.
+func startupInfoQuote(val string) string {
+	if !strings.ContainsAny(val, " \"\\") {
+		return val
+	}
+	val = strings.Replace(val, "\\", "\\\\", -1)
+	val = strings.Replace(val, "\"", "\\\"", -1)
+	return "\"" + val + "\""
+}
@@ -0,0 +1,72 @@
+package ewmh
+
+import (
+	"github.com/jezek/xgbutil"
+)
+
+// DesktopViewportFor returns the top-left corner of the viewport of
+// 'desktop', which is found in the per-desktop _NET_DESKTOP_VIEWPORT array.
+// If the property isn't set, or has no entry for 'desktop', the viewport is
+// at (0, 0), which is what window managers without large desktops use.
+func DesktopViewportFor(xu *xgbutil.XUtil, desktop int) DesktopViewport {
+	viewports, err := DesktopViewportGet(xu)
+	if err != nil || desktop < 0 || desktop >= len(viewports) {
+		return DesktopViewport{}
+	}
+	return viewports[desktop]
+}
+
+// ViewportPosition maps the position (x, y) of a window on 'desktop' to the
+// desktop a pager should show it on and its position relative to that
+// desktop. (x, y) should be relative to the root window, and 'desktop' should
+// be the window's _NET_WM_DESKTOP.
+//
+// Some window managers use a single desktop that is larger than the screen
+// (its size is in _NET_DESKTOP_GEOMETRY), and only show the part of it at
+// the desktop's viewport. In that case, each screen sized part of the large
+// desktop is treated as a separate desktop, numbered left to right and then
+// top to bottom, which is how pagers usually show them. Window managers with
+// desktops the size of the screen have one such part per desktop, so 'desktop'
+// and (x, y) are returned unchanged.
+func ViewportPosition(xu *xgbutil.XUtil, desktop, x, y int) (int, int, int) {
+	screenWidth := int(xu.Screen().WidthInPixels)
+	screenHeight := int(xu.Screen().HeightInPixels)
+
+	cols, rows := 1, 1
+	if geom, err := DesktopGeometryGet(xu); err == nil {
+		if geom.Width > screenWidth {
+			cols = geom.Width / screenWidth
+		}
+		if geom.Height > screenHeight {
+			rows = geom.Height / screenHeight
+		}
+	}
+	if cols == 1 && rows == 1 {
+		return desktop, x, y
+	}
+
+	// Window positions are relative to the viewport, so they need to be made
+	// absolute on the large desktop first.
+	viewport := DesktopViewportFor(xu, desktop)
+	x, y = x+viewport.X, y+viewport.Y
+
+	col := clampDiv(x, screenWidth, cols)
+	row := clampDiv(y, screenHeight, rows)
+	return desktop*cols*rows + row*cols + col,
+		x - col*screenWidth, y - row*screenHeight
+}
+
+// clampDiv returns the floor of n / d, clamped to [0, limit).
+func clampDiv(n, d, limit int) int {
+	q := n / d
+	if n < 0 && n%d != 0 {
+		q--
+	}
+	if q < 0 {
+		return 0
+	}
+	if q >= limit {
+		return limit - 1
+	}
+	return q
+}
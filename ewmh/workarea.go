@@ -0,0 +1,99 @@
+package ewmh
+
+import (
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xrect"
+)
+
+// WorkArea computes the usable area of each desktop by subtracting the struts
+// of every client in _NET_CLIENT_LIST from the root window geometry. The
+// returned slice has one rectangle for each desktop in
+// _NET_NUMBER_OF_DESKTOPS. (Or a single rectangle if that property isn't
+// set.)
+//
+// A client's struts are applied to the desktop in its _NET_WM_DESKTOP
+// property, or to all desktops if that property is 0xFFFFFFFF or missing.
+// _NET_WM_STRUT_PARTIAL is used if it is set. Otherwise _NET_WM_STRUT is used.
+//
+// This is useful for pagers and panels that need to compute the work area
+// themselves. Window managers usually publish it in _NET_WORKAREA instead.
+// Note that the work area of each physical head can be found by passing the
+// head geometries to xrect.ApplyStrut instead. (See the workarea-struts
+// example.)
+func WorkArea(xu *xgbutil.XUtil) ([]xrect.Rect, error) {
+	clients, err := ClientListGet(xu)
+	if err != nil {
+		return nil, err
+	}
+
+	numDesks, err := NumberOfDesktopsGet(xu)
+	if err != nil || numDesks == 0 {
+		numDesks = 1
+	}
+
+	rootWidth := uint(xu.Screen().WidthInPixels)
+	rootHeight := uint(xu.Screen().HeightInPixels)
+	areas := make([]xrect.Rect, numDesks)
+	for i := range areas {
+		areas[i] = xrect.New(0, 0, int(rootWidth), int(rootHeight))
+	}
+
+	for _, client := range clients {
+		strut := clientStrut(xu, client, rootWidth, rootHeight)
+		if strut == nil {
+			continue
+		}
+
+		desk, err := WmDesktopGet(xu, client)
+		if err != nil {
+			desk = 0xFFFFFFFF
+		}
+		for i, area := range areas {
+			if desk != 0xFFFFFFFF && desk != uint(i) {
+				continue
+			}
+			xrect.ApplyStrut([]xrect.Rect{area}, rootWidth, rootHeight,
+				strut.Left, strut.Right, strut.Top, strut.Bottom,
+				strut.LeftStartY, strut.LeftEndY,
+				strut.RightStartY, strut.RightEndY,
+				strut.TopStartX, strut.TopEndX,
+				strut.BottomStartX, strut.BottomEndX)
+		}
+	}
+	return areas, nil
+}
+
+// clientStrut returns the partial strut of a client, or nil if it has none.
+// A _NET_WM_STRUT value is converted to a partial strut that spans the whole
+// edge of the root window.
+func clientStrut(xu *xgbutil.XUtil, win xproto.Window,
+	rootWidth, rootHeight uint) *WmStrutPartial {
+
+	if strut, err := WmStrutPartialGet(xu, win); err == nil {
+		return strut
+	}
+
+	strut, err := WmStrutGet(xu, win)
+	if err != nil {
+		return nil
+	}
+	partial := &WmStrutPartial{
+		Left: strut.Left, Right: strut.Right,
+		Top: strut.Top, Bottom: strut.Bottom,
+	}
+	if strut.Left > 0 {
+		partial.LeftEndY = rootHeight - 1
+	}
+	if strut.Right > 0 {
+		partial.RightEndY = rootHeight - 1
+	}
+	if strut.Top > 0 {
+		partial.TopEndX = rootWidth - 1
+	}
+	if strut.Bottom > 0 {
+		partial.BottomEndX = rootWidth - 1
+	}
+	return partial
+}
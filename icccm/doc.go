@@ -4,7 +4,8 @@ and setters for many of the properties specified in the ICCCM. There is also a
 smattering of support for other protocols specified by ICCCM. For example, to
 satisfy the WM_DELETE_WINDOW protocol, package icccm provides 'IsDeleteProtocol'
 which returns whether a ClientMessage event satisfies the WM_DELETE_WINDOW
-protocol.
+protocol. 'HandleDeleteWindow' sets up a client window to handle both
+WM_DELETE_WINDOW and _NET_WM_PING.
 
 If a property has values that aren't simple strings or integers, struct types
 are provided to organize the data. In particular, WM_NORMAL_HINTS and WM_HINTS.
@@ -27,6 +28,14 @@ NormalHints struct are WidthInc and HeightInc. So to check if such values exist
 		// Use normalHints.WidthInc and normalHints.HeightInc
 	}
 
+When setting WM_NORMAL_HINTS, the methods of NormalHints (like MinSizeSet and
+ResizeIncSet) set the appropriate bit in Flags for you:
+
+	normalHints := &icccm.NormalHints{}
+	normalHints.MinSizeSet(100, 50)
+	normalHints.ResizeIncSet(8, 16)
+	err := icccm.WmNormalHintsSet(XUtilValue, window-id, normalHints)
+
 When you should use icccm
 
 Although the ICCCM is extremely old, a lot of it is still used. In fact, the
@@ -2,6 +2,8 @@ package icccm
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/jezek/xgb/xproto"
 
@@ -34,6 +36,8 @@ const (
 	SizeHintPWinGravity
 )
 
+// The possible values of the state in WM_STATE. (StateZoomed and
+// StateInactive are obsolete and shouldn't be used.)
 const (
 	StateWithdrawn = iota
 	StateNormal
@@ -48,8 +52,9 @@ func WmNameGet(xu *xgbutil.XUtil, win xproto.Window) (string, error) {
 }
 
 // WM_NAME set
+// The name is stored as Latin-1 when possible. (See xprop.ChangePropStr.)
 func WmNameSet(xu *xgbutil.XUtil, win xproto.Window, name string) error {
-	return xprop.ChangeProp(xu, win, 8, "WM_NAME", "STRING", ([]byte)(name))
+	return xprop.ChangePropStr(xu, win, "WM_NAME", name)
 }
 
 // WM_ICON_NAME get
@@ -58,9 +63,9 @@ func WmIconNameGet(xu *xgbutil.XUtil, win xproto.Window) (string, error) {
 }
 
 // WM_ICON_NAME set
+// The name is stored as Latin-1 when possible. (See xprop.ChangePropStr.)
 func WmIconNameSet(xu *xgbutil.XUtil, win xproto.Window, name string) error {
-	return xprop.ChangeProp(xu, win, 8, "WM_ICON_NAME", "STRING",
-		([]byte)(name))
+	return xprop.ChangePropStr(xu, win, "WM_ICON_NAME", name)
 }
 
 // NormalHints is a struct that organizes the information related to the
@@ -117,11 +122,82 @@ func WmNormalHintsGet(xu *xgbutil.XUtil,
 	return nh, nil
 }
 
+// The following methods set fields in a NormalHints struct along with the
+// corresponding bit in Flags. Using them instead of setting the fields
+// directly makes it impossible to forget about Flags.
+
+// PositionSet sets X and Y, and the SizeHintPPosition flag.
+func (nh *NormalHints) PositionSet(x, y int) {
+	nh.X, nh.Y = x, y
+	nh.Flags |= SizeHintPPosition
+}
+
+// SizeSet sets Width and Height, and the SizeHintPSize flag.
+func (nh *NormalHints) SizeSet(width, height uint) {
+	nh.Width, nh.Height = width, height
+	nh.Flags |= SizeHintPSize
+}
+
+// MinSizeSet sets MinWidth and MinHeight, and the SizeHintPMinSize flag.
+func (nh *NormalHints) MinSizeSet(width, height uint) {
+	nh.MinWidth, nh.MinHeight = width, height
+	nh.Flags |= SizeHintPMinSize
+}
+
+// MaxSizeSet sets MaxWidth and MaxHeight, and the SizeHintPMaxSize flag.
+func (nh *NormalHints) MaxSizeSet(width, height uint) {
+	nh.MaxWidth, nh.MaxHeight = width, height
+	nh.Flags |= SizeHintPMaxSize
+}
+
+// ResizeIncSet sets WidthInc and HeightInc, and the SizeHintPResizeInc flag.
+func (nh *NormalHints) ResizeIncSet(widthInc, heightInc uint) {
+	nh.WidthInc, nh.HeightInc = widthInc, heightInc
+	nh.Flags |= SizeHintPResizeInc
+}
+
+// AspectSet sets the minimum and maximum aspect ratios, and the
+// SizeHintPAspect flag.
+func (nh *NormalHints) AspectSet(minNum, minDen, maxNum, maxDen uint) {
+	nh.MinAspectNum, nh.MinAspectDen = minNum, minDen
+	nh.MaxAspectNum, nh.MaxAspectDen = maxNum, maxDen
+	nh.Flags |= SizeHintPAspect
+}
+
+// BaseSizeSet sets BaseWidth and BaseHeight, and the SizeHintPBaseSize flag.
+func (nh *NormalHints) BaseSizeSet(width, height uint) {
+	nh.BaseWidth, nh.BaseHeight = width, height
+	nh.Flags |= SizeHintPBaseSize
+}
+
+// WinGravitySet sets WinGravity, and the SizeHintPWinGravity flag.
+func (nh *NormalHints) WinGravitySet(gravity uint) {
+	nh.WinGravity = gravity
+	nh.Flags |= SizeHintPWinGravity
+}
+
 // WM_NORMAL_HINTS set
 // Make sure to set the flags in the NormalHints struct correctly!
+// (The *Set methods of NormalHints do this for you.)
+// An error is returned if the minimum size is bigger than the maximum size,
+// or if an aspect ratio has a zero denominator.
 func WmNormalHintsSet(xu *xgbutil.XUtil, win xproto.Window,
 	nh *NormalHints) error {
 
+	if nh.Flags&SizeHintPMinSize > 0 && nh.Flags&SizeHintPMaxSize > 0 {
+		if nh.MinWidth > nh.MaxWidth || nh.MinHeight > nh.MaxHeight {
+			return fmt.Errorf("WmNormalHintsSet: The minimum size (%d, %d) "+
+				"is bigger than the maximum size (%d, %d).",
+				nh.MinWidth, nh.MinHeight, nh.MaxWidth, nh.MaxHeight)
+		}
+	}
+	if nh.Flags&SizeHintPAspect > 0 {
+		if nh.MinAspectDen == 0 || nh.MaxAspectDen == 0 {
+			return fmt.Errorf("WmNormalHintsSet: Aspect ratios must have " +
+				"non-zero denominators.")
+		}
+	}
+
 	raw := []uint{
 		nh.Flags,
 		uint(nh.X), uint(nh.Y), nh.Width, nh.Height,
@@ -214,10 +290,13 @@ func WmClassGet(xu *xgbutil.XUtil, win xproto.Window) (*WmClass, error) {
 }
 
 // WM_CLASS set
+// The instance and class are converted to Latin-1. (See xprop.UTF8ToLatin1.)
 func WmClassSet(xu *xgbutil.XUtil, win xproto.Window, class *WmClass) error {
-	raw := make([]byte, len(class.Instance)+len(class.Class)+2)
-	copy(raw, class.Instance)
-	copy(raw[(len(class.Instance)+1):], class.Class)
+	instance := xprop.UTF8ToLatin1(class.Instance)
+	cls := xprop.UTF8ToLatin1(class.Class)
+	raw := make([]byte, len(instance)+len(cls)+2)
+	copy(raw, instance)
+	copy(raw[(len(instance)+1):], cls)
 
 	return xprop.ChangeProp(xu, win, 8, "WM_CLASS", "STRING", raw)
 }
@@ -263,6 +342,8 @@ func WmColormapWindowsGet(xu *xgbutil.XUtil,
 }
 
 // WM_COLORMAP_WINDOWS set
+// The windows are listed in decreasing order of colormap priority. If the
+// top-level window isn't in the list, the window manager assumes it is first.
 func WmColormapWindowsSet(xu *xgbutil.XUtil, win xproto.Window,
 	windows []xproto.Window) error {
 
@@ -276,16 +357,55 @@ func WmClientMachineGet(xu *xgbutil.XUtil, win xproto.Window) (string, error) {
 }
 
 // WM_CLIENT_MACHINE set
+// The name is stored as Latin-1 when possible. (See xprop.ChangePropStr.)
 func WmClientMachineSet(xu *xgbutil.XUtil, win xproto.Window,
 	client string) error {
 
-	return xprop.ChangeProp(xu, win, 8, "WM_CLIENT_MACHINE", "STRING",
-		([]byte)(client))
+	return xprop.ChangePropStr(xu, win, "WM_CLIENT_MACHINE", client)
+}
+
+// WindowPidLocal returns the process id in the _NET_WM_PID property of a
+// window, but only if the WM_CLIENT_MACHINE property of the window matches
+// the host name of this machine. Otherwise, the pid belongs to a process on
+// some other machine, and false is returned.
+// Host names match if they are equal, or if one of them is the first
+// component of the other. (i.e., "foo" matches "foo.example.com".)
+func WindowPidLocal(xu *xgbutil.XUtil, win xproto.Window) (int, bool, error) {
+	machine, err := WmClientMachineGet(xu, win)
+	if err != nil {
+		return 0, false, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return 0, false, fmt.Errorf("WindowPidLocal: Could not get the host "+
+			"name: %s", err)
+	}
+	if !sameHost(machine, hostname) {
+		return 0, false, nil
+	}
+
+	pid, err := xprop.PropValNum(xprop.GetProperty(xu, win, "_NET_WM_PID"))
+	if err != nil {
+		return 0, false, err
+	}
+	return int(pid), true, nil
+}
+
+// sameHost returns whether two host names refer to the same machine.
+func sameHost(host1, host2 string) bool {
+	host1, host2 = strings.ToLower(host1), strings.ToLower(host2)
+	if host1 == host2 {
+		return true
+	}
+	short1 := strings.SplitN(host1, ".", 2)[0]
+	short2 := strings.SplitN(host2, ".", 2)[0]
+	return (host1 == short1 || host2 == short2) && short1 == short2
 }
 
 // WmState is a struct that organizes information related to the WM_STATE
-// property. Namely, the state (corresponding to a State* constant in this file)
+// property. Namely, the state (StateWithdrawn, StateNormal or StateIconic)
 // and the icon window (probably not used).
+// Window managers must set WM_STATE on every client they manage.
 type WmState struct {
 	State uint
 	Icon  xproto.Window
@@ -299,7 +419,7 @@ func WmStateGet(xu *xgbutil.XUtil, win xproto.Window) (*WmState, error) {
 	}
 	if len(raw) != 2 {
 		return nil,
-			fmt.Errorf("WmState: Expected two integers in WM_STATE property "+
+			fmt.Errorf("WmStateGet: Expected two integers in WM_STATE property "+
 				"but xgbutil found %d in '%v'.", len(raw), raw)
 	}
 
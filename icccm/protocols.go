@@ -62,3 +62,78 @@ func IsFocusProtocol(X *xgbutil.XUtil, ev xevent.ClientMessageEvent) bool {
 
 	return true
 }
+
+// IsPingProtocol checks whether a ClientMessage event is a _NET_WM_PING
+// request from the window manager. (_NET_WM_PING is defined by the EWMH, but
+// it is sent as a WM_PROTOCOLS message like the ICCCM protocols.)
+func IsPingProtocol(X *xgbutil.XUtil, ev xevent.ClientMessageEvent) bool {
+	if ev.Format != 32 {
+		return false
+	}
+
+	typeName, err := xprop.AtomName(X, ev.Type)
+	if err != nil || typeName != "WM_PROTOCOLS" {
+		return false
+	}
+
+	protocolType, err := xprop.AtomName(X,
+		xproto.Atom(ev.Data.Data32[0]))
+	if err != nil || protocolType != "_NET_WM_PING" {
+		return false
+	}
+
+	return true
+}
+
+// HandleDeleteWindow does all the necessary setup for a client window to
+// support the WM_DELETE_WINDOW and _NET_WM_PING protocols. Both protocols
+// are added to the WM_PROTOCOLS property of 'win' (keeping any protocols
+// already there), and a ClientMessage handler is connected to 'win'.
+// 'cb' is run when the window manager asks for the window to be closed.
+// (It should probably just clean up and destroy the window.) Pings from the
+// window manager are answered automatically, so that the window manager
+// knows the client is still responding.
+// The handler can be removed with xevent.Detach.
+//
+// Note that if you're using the xwindow package, WMGracefulClose does the
+// same thing for WM_DELETE_WINDOW only.
+func HandleDeleteWindow(X *xgbutil.XUtil, win xproto.Window,
+	cb func()) error {
+
+	prots, _ := WmProtocolsGet(X, win)
+	newProts := prots
+	for _, want := range []string{"WM_DELETE_WINDOW", "_NET_WM_PING"} {
+		found := false
+		for _, prot := range prots {
+			if prot == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			newProts = append(newProts, want)
+		}
+	}
+	if len(newProts) > len(prots) {
+		if err := WmProtocolsSet(X, win, newProts); err != nil {
+			return err
+		}
+	}
+
+	xevent.ClientMessageFun(
+		func(X *xgbutil.XUtil, ev xevent.ClientMessageEvent) {
+			if IsDeleteProtocol(X, ev) {
+				cb()
+			} else if IsPingProtocol(X, ev) {
+				// The reply is the same message, sent back to the root
+				// window.
+				reply := *ev.ClientMessageEvent
+				reply.Window = X.RootWin()
+				evMask := xproto.EventMaskSubstructureNotify |
+					xproto.EventMaskSubstructureRedirect
+				xproto.SendEvent(X.Conn(), false, X.RootWin(),
+					uint32(evMask), string(reply.Bytes()))
+			}
+		}).Connect(X, win)
+	return nil
+}
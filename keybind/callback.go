@@ -2,6 +2,7 @@ package keybind
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/jezek/xgb/xproto"
 
@@ -9,6 +10,10 @@ import (
 	"github.com/jezek/xgbutil/xevent"
 )
 
+func init() {
+	xevent.DetachHook(Detach)
+}
+
 // connect is essentially 'Connect' for either KeyPress or KeyRelease events.
 // Namely, it parses the key string, issues a grab request if necessary,
 // sets up the appropriate event handlers for the main event loop, and attaches
@@ -24,7 +29,7 @@ func connect(xu *xgbutil.XUtil, callback xgbutil.CallbackKey,
 
 	// Only do the grab if we haven't yet on this window.
 	for _, keycode := range keycodes {
-		if grab && keyBindGrabs(xu, evtype, win, mods, keycode) == 0 {
+		if grab && keyGrabs(xu, win, mods, keycode) == 0 {
 			if err := GrabChecked(xu, win, mods, keycode); err != nil {
 				// If a bad access, let's be nice and give a good error message.
 				switch err.(type) {
@@ -93,10 +98,43 @@ func (callback KeyPressFun) Connect(xu *xgbutil.XUtil, win xproto.Window,
 	return connect(xu, callback, xevent.KeyPress, win, keyStr, grab, false)
 }
 
+// ConnectKeysym is the same as Connect, except the key binding is given as
+// a (modifiers, keysym) tuple instead of a key string. This is useful when
+// key symbols are computed rather than read from a configuration.
+func (callback KeyPressFun) ConnectKeysym(xu *xgbutil.XUtil,
+	win xproto.Window, mods uint16, keysym xproto.Keysym, grab bool) error {
+
+	keyStr, err := keysymString(mods, keysym)
+	if err != nil {
+		return err
+	}
+	return connect(xu, callback, xevent.KeyPress, win, keyStr, grab, false)
+}
+
 func (callback KeyPressFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(xevent.KeyPressEvent))
 }
 
+// ownedKeyPress is a key press binding that attaches handlers of its own
+// besides the binding itself. (i.e., to watch key releases.) 'detach'
+// removes them, and is run when the binding is detached.
+type ownedKeyPress struct {
+	KeyPressFun
+	detach func(xu *xgbutil.XUtil)
+}
+
+// detachOwned removes the handlers attached by any owned bindings in 'cbs'.
+// A binding may appear more than once (once for each of its keycodes), but
+// its handlers are only removed once.
+func detachOwned(xu *xgbutil.XUtil, cbs []xgbutil.CallbackKey) {
+	for _, cb := range cbs {
+		if owned, ok := cb.(*ownedKeyPress); ok && owned.detach != nil {
+			owned.detach(xu)
+			owned.detach = nil
+		}
+	}
+}
+
 // KeyReleaseFun represents a function that is called when a particular key
 // binding is fired.
 type KeyReleaseFun xevent.KeyReleaseFun
@@ -107,10 +145,44 @@ func (callback KeyReleaseFun) Connect(xu *xgbutil.XUtil, win xproto.Window,
 	return connect(xu, callback, xevent.KeyRelease, win, keyStr, grab, false)
 }
 
+// ConnectKeysym is the same as Connect, except the key binding is given as
+// a (modifiers, keysym) tuple instead of a key string.
+func (callback KeyReleaseFun) ConnectKeysym(xu *xgbutil.XUtil,
+	win xproto.Window, mods uint16, keysym xproto.Keysym, grab bool) error {
+
+	keyStr, err := keysymString(mods, keysym)
+	if err != nil {
+		return err
+	}
+	return connect(xu, callback, xevent.KeyRelease, win, keyStr, grab, false)
+}
+
 func (callback KeyReleaseFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(xevent.KeyReleaseEvent))
 }
 
+// ConnectPressRelease attaches 'onPress' to key press events and 'onRelease'
+// to key release events of the same key string on the window provided. (i.e.,
+// to show something only while a key is held down.) If 'grab' is true, the
+// key is grabbed once for both handlers.
+// Note that key press and key release handlers connected separately also
+// share a single grab, which is only released when both are detached.
+// If 'onRelease' can't be connected, the key press handlers for the key
+// string on the window are detached again, so that nothing is left half
+// connected.
+func ConnectPressRelease(xu *xgbutil.XUtil, win xproto.Window, keyStr string,
+	grab bool, onPress KeyPressFun, onRelease KeyReleaseFun) error {
+
+	if err := onPress.Connect(xu, win, keyStr, grab); err != nil {
+		return err
+	}
+	if err := onRelease.Connect(xu, win, keyStr, grab); err != nil {
+		DetachPressString(xu, win, keyStr)
+		return err
+	}
+	return nil
+}
+
 // runKeyPressCallbacks infers the window, keycode and modifiers from a
 // KeyPressEvent and runs the corresponding callbacks.
 func runKeyPressCallbacks(xu *xgbutil.XUtil, ev xevent.KeyPressEvent) {
@@ -127,6 +199,42 @@ func runKeyReleaseCallbacks(xu *xgbutil.XUtil, ev xevent.KeyReleaseEvent) {
 	runKeyBindCallbacks(xu, ev, xevent.KeyRelease, ev.Event, mods, kc)
 }
 
+// GrabbedKeys returns the key strings that are passively grabbed on the
+// provided window by this package, for both key press and key release
+// events. Each key string is reported once (as it was given to Connect),
+// and the result is sorted.
+// The server is not queried, so this only reflects bindings made with
+// Connect.
+func GrabbedKeys(xu *xgbutil.XUtil, win xproto.Window) []string {
+	xu.KeybindsLck.RLock()
+	keyStrs := make([]xgbutil.KeyString, len(xu.Keystrings))
+	copy(keyStrs, xu.Keystrings)
+	xu.KeybindsLck.RUnlock()
+
+	seen := make(map[string]bool)
+	grabbed := make([]string, 0)
+	for _, ks := range keyStrs {
+		if ks.Win != win || !ks.Grab || seen[ks.Str] {
+			continue
+		}
+
+		// The key string could have been detached since it was connected.
+		mods, keycodes, err := ParseString(xu, ks.Str)
+		if err != nil {
+			continue
+		}
+		for _, keycode := range keycodes {
+			if keyBindGrabs(xu, ks.Evtype, win, mods, keycode) > 0 {
+				seen[ks.Str] = true
+				grabbed = append(grabbed, ks.Str)
+				break
+			}
+		}
+	}
+	sort.Strings(grabbed)
+	return grabbed
+}
+
 // Detach removes all handlers for all key events for the provided window id.
 // This should be called whenever a window is no longer receiving events to make
 // sure the garbage collector can release memory used to store the handler info.
@@ -147,14 +255,54 @@ func DetachRelease(xu *xgbutil.XUtil, win xproto.Window) {
 	detach(xu, xevent.KeyRelease, win)
 }
 
+// DetachPressString removes all handlers for key *press* events on the
+// provided window that are attached to the given key string. Handlers for
+// other key strings on the same window are left intact.
+// The key string must be written the same way it was when it was connected.
+func DetachPressString(xu *xgbutil.XUtil, win xproto.Window,
+	keyStr string) error {
+
+	return detachString(xu, xevent.KeyPress, win, keyStr)
+}
+
+// DetachReleaseString is the same as DetachPressString, except it only
+// removes handlers for key *release* events.
+func DetachReleaseString(xu *xgbutil.XUtil, win xproto.Window,
+	keyStr string) error {
+
+	return detachString(xu, xevent.KeyRelease, win, keyStr)
+}
+
+// detachString removes all handlers for the provided window, event type and
+// key string combination. An ungrab request is issued for each grab that
+// drops to zero. (A grab is shared by key press and key release handlers.)
+func detachString(xu *xgbutil.XUtil, evtype int, win xproto.Window,
+	keyStr string) error {
+
+	mods, keycodes, err := ParseString(xu, keyStr)
+	if err != nil {
+		return err
+	}
+
+	removeKeyString(xu, evtype, win, keyStr)
+	for _, keycode := range keycodes {
+		key := xgbutil.KeyKey{evtype, win, mods, keycode}
+		detachOwned(xu, detachKeyBindKey(xu, key))
+		if keyGrabs(xu, win, mods, keycode) == 0 {
+			Ungrab(xu, win, mods, keycode)
+		}
+	}
+	return nil
+}
+
 // detach removes all handlers for the provided window and event type
 // combination. This will also issue an ungrab request for each grab that
 // drops to zero.
 func detach(xu *xgbutil.XUtil, evtype int, win xproto.Window) {
 	mkeys := keyKeys(xu)
-	detachKeyBindWindow(xu, evtype, win)
+	detachOwned(xu, detachKeyBindWindow(xu, evtype, win))
 	for _, key := range mkeys {
-		if keyBindGrabs(xu, key.Evtype, key.Win, key.Mod, key.Code) == 0 {
+		if keyGrabs(xu, key.Win, key.Mod, key.Code) == 0 {
 			Ungrab(xu, key.Win, key.Mod, key.Code)
 		}
 	}
@@ -0,0 +1,161 @@
+package keybind
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xevent"
+)
+
+// KeyChordFun represents a function that is called when a sequence of key
+// presses has been completed. (i.e., Emacs style chords like
+// "Mod4-x Mod4-c".)
+type KeyChordFun xevent.KeyPressFun
+
+// Connect attaches the chord callback to the sequence of key strings on the
+// window provided. The first key string is bound like any other key binding.
+// When it is pressed, the keyboard is grabbed on 'win' and the remaining key
+// strings must be pressed in order, each within 'timeout' of the last.
+// Pressing any other key (that isn't itself a modifier) or letting the
+// timeout expire cancels the chord and releases the keyboard.
+// The callback is run with the KeyPress event of the last key in the chord.
+//
+// Note that the keys following the first one are still sent to any other
+// key bindings on 'win'. The handlers attached to watch them are removed
+// along with the binding by Detach, DetachPress or DetachPressString.
+func (callback KeyChordFun) Connect(xu *xgbutil.XUtil, win xproto.Window,
+	keyStrs []string, timeout time.Duration, grab bool) error {
+
+	if len(keyStrs) == 0 {
+		return fmt.Errorf("KeyChordFun.Connect: No key strings were given.")
+	}
+	for _, keyStr := range keyStrs {
+		if _, _, err := ParseString(xu, keyStr); err != nil {
+			return err
+		}
+	}
+
+	kc := &keyChord{keyStrs: keyStrs}
+	var err error
+	kc.timer, err = xevent.NewTimer(xu, kc.disarm)
+	if err != nil {
+		return err
+	}
+	press := xevent.ConnectHandle(xu, xevent.KeyPress, win,
+		xevent.KeyPressFun(func(xu *xgbutil.XUtil, ev xevent.KeyPressEvent) {
+			if kc.step(xu, ev, timeout) {
+				callback(xu, ev)
+			}
+		}))
+
+	owned := &ownedKeyPress{
+		KeyPressFun: func(xu *xgbutil.XUtil, ev xevent.KeyPressEvent) {
+			if len(kc.keyStrs) == 1 {
+				callback(xu, ev)
+				return
+			}
+			kc.arm(xu, win, ev, timeout)
+		},
+		detach: func(xu *xgbutil.XUtil) {
+			kc.disarm(xu)
+			kc.timer.Detach(xu)
+			xevent.DetachHandle(xu, press)
+		},
+	}
+	err = connect(xu, owned, xevent.KeyPress, win, keyStrs[0], grab, false)
+	if err != nil {
+		owned.detach(xu)
+		return err
+	}
+	return nil
+}
+
+// keyChord keeps track of the state of a single chord binding.
+// It is only modified from inside the main event loop.
+type keyChord struct {
+	keyStrs []string
+	next    int // index into keyStrs, or 0 if the chord isn't armed
+	last    xevent.KeyPressEvent
+	timer   *xevent.Timer
+}
+
+// arm grabs the keyboard and waits for the rest of the chord.
+func (kc *keyChord) arm(xu *xgbutil.XUtil, win xproto.Window,
+	ev xevent.KeyPressEvent, timeout time.Duration) {
+
+	kc.disarm(xu)
+	if err := SmartGrab(xu, win); err != nil {
+		xgbutil.Logger.Printf("KeyChordFun: Could not start chord: %s", err)
+		return
+	}
+	kc.next = 1
+	kc.last = ev
+	kc.timer.Reset(xu, timeout)
+}
+
+// step processes a key press while the chord is armed, and returns true when
+// the chord has been completed.
+func (kc *keyChord) step(xu *xgbutil.XUtil, ev xevent.KeyPressEvent,
+	timeout time.Duration) bool {
+
+	if kc.next == 0 {
+		return false
+	}
+
+	// The key press that armed the chord is seen here too.
+	if ev.Time == kc.last.Time && ev.Detail == kc.last.Detail {
+		return false
+	}
+
+	// Modifier keys are pressed on the way to the next key in the chord.
+	if ModGet(xu, ev.Detail) != 0 {
+		return false
+	}
+
+	elapsed := time.Duration(ev.Time-kc.last.Time) * time.Millisecond
+	if elapsed > timeout || !kc.matches(xu, kc.keyStrs[kc.next], ev) {
+		kc.disarm(xu)
+		return false
+	}
+
+	kc.next++
+	kc.last = ev
+	if kc.next == len(kc.keyStrs) {
+		kc.disarm(xu)
+		return true
+	}
+	kc.timer.Reset(xu, timeout)
+	return false
+}
+
+// matches returns true if the key press corresponds to the key string.
+func (kc *keyChord) matches(xu *xgbutil.XUtil, keyStr string,
+	ev xevent.KeyPressEvent) bool {
+
+	mods, keycodes, err := ParseString(xu, keyStr)
+	if err != nil {
+		return false
+	}
+	evMods, evCode := DeduceKeyInfo(ev.State, ev.Detail)
+	if evMods != mods {
+		return false
+	}
+	for _, keycode := range keycodes {
+		if keycode == evCode {
+			return true
+		}
+	}
+	return false
+}
+
+// disarm cancels the chord and releases the keyboard if it was armed.
+func (kc *keyChord) disarm(xu *xgbutil.XUtil) {
+	kc.timer.Stop()
+	if kc.next > 0 {
+		SmartUngrab(xu)
+		kc.next = 0
+	}
+}
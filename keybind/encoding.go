@@ -126,3 +126,86 @@ func interpretSymList(xu *xgbutil.XUtil, keycode xproto.Keycode) (
 
 	return
 }
+
+// LookupText is like LookupString, except it returns the UTF-8 text that
+// would be typed by the (modifiers, keycode) tuple instead of the name of
+// the key symbol. (i.e., "é" instead of "eacute" and "\r" instead of
+// "Return".)
+// An empty string is returned if the key doesn't produce any text, like
+// a function or modifier key.
+// The same caveats as LookupString apply.
+func LookupText(xu *xgbutil.XUtil, mods uint16,
+	keycode xproto.Keycode) string {
+
+	r1 := KeysymToRune(KeysymGet(xu, keycode, 0))
+	r2 := KeysymToRune(KeysymGet(xu, keycode, 1))
+
+	// If there is no second key symbol, it is derived from the first.
+	if r2 == 0 {
+		if unicode.IsLetter(r1) {
+			r1, r2 = unicode.ToLower(r1), unicode.ToUpper(r1)
+		} else {
+			r2 = r1
+		}
+	}
+
+	var r rune
+	shift := mods&xproto.ModMaskShift > 0
+	lock := mods&xproto.ModMaskLock > 0
+	switch {
+	case !shift && !lock:
+		r = r1
+	case !shift && lock:
+		r = unicode.ToUpper(r1)
+	case shift && lock:
+		r = unicode.ToUpper(r2)
+	case shift:
+		r = r2
+	}
+
+	if r == 0 {
+		return ""
+	}
+	return string(r)
+}
+
+// KeysymToRune converts a keysym to the Unicode character it represents.
+// This covers the Latin-1 key symbols, the key symbols that directly encode
+// a Unicode code point, the keypad and a few control keys. (i.e., Return,
+// Tab and BackSpace.)
+// If the keysym doesn't represent a character, 0 is returned.
+func KeysymToRune(keysym xproto.Keysym) rune {
+	switch {
+	case keysym >= 0x20 && keysym <= 0x7e, keysym >= 0xa0 && keysym <= 0xff:
+		// Latin-1 key symbols are the same as their code points.
+		return rune(keysym)
+	case keysym >= 0x1000100 && keysym <= 0x110ffff:
+		// Key symbols for any other code point have this offset.
+		return rune(keysym - 0x1000000)
+	case keysym >= 0xffb0 && keysym <= 0xffb9:
+		// KP_0 through KP_9
+		return rune('0' + keysym - 0xffb0)
+	}
+	return specialKeysymRunes[keysym]
+}
+
+// specialKeysymRunes maps key symbols that produce text, but aren't in
+// the Latin-1 or Unicode ranges, to the characters they produce.
+var specialKeysymRunes = map[xproto.Keysym]rune{
+	0xff08: '\b',   // BackSpace
+	0xff09: '\t',   // Tab
+	0xff0d: '\r',   // Return
+	0xff1b: '\x1b', // Escape
+	0xffff: '\x7f', // Delete
+	0xff80: ' ',    // KP_Space
+	0xff89: '\t',   // KP_Tab
+	0xff8d: '\r',   // KP_Enter
+	0xffaa: '*',    // KP_Multiply
+	0xffab: '+',    // KP_Add
+	0xffac: ',',    // KP_Separator
+	0xffad: '-',    // KP_Subtract
+	0xffae: '.',    // KP_Decimal
+	0xffaf: '/',    // KP_Divide
+	0xffbd: '=',    // KP_Equal
+	0x20ac: '€',    // EuroSign
+}
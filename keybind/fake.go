@@ -0,0 +1,137 @@
+package keybind
+
+import (
+	"fmt"
+
+	"github.com/jezek/xgb/xproto"
+	"github.com/jezek/xgb/xtest"
+
+	"github.com/jezek/xgbutil"
+)
+
+// FakeInput sends a synthetic key press (or release, if 'press' is false) of
+// the key string 'keyStr' (i.e., "control-c") to the X server, as if the key
+// was pressed on the keyboard. The modifiers in the key string are pressed
+// before the key, and released after the key is released.
+// The XTEST extension is used if it is available, in which case the event
+// goes wherever a real key press would. Otherwise, a KeyPress or KeyRelease
+// event is sent to the window with the input focus with SendEvent. (Some
+// clients ignore events sent this way.)
+// keybind.Initialize MUST have been called before using this function.
+func FakeInput(xu *xgbutil.XUtil, keyStr string, press bool) error {
+	mods, kcs, err := ParseString(xu, keyStr)
+	if err != nil {
+		return err
+	}
+	mods &^= xproto.ModMaskAny
+
+	if !xu.ExtInitialized("XTEST") {
+		if err := xtest.Init(xu.Conn()); err != nil {
+			return fakeSendEvent(xu, mods, kcs[0], press)
+		}
+	}
+
+	// Modifiers are pressed first and released last.
+	var modCodes []xproto.Keycode
+	for _, mod := range Modifiers {
+		if mods&mod > 0 {
+			code := modKeycode(xu, mod)
+			if code == 0 {
+				return fmt.Errorf("FakeInput: No key is mapped to the "+
+					"modifier %s.", ModifierString(mod))
+			}
+			modCodes = append(modCodes, code)
+		}
+	}
+
+	if press {
+		for _, code := range modCodes {
+			if err := fakeXTest(xu, code, true); err != nil {
+				return err
+			}
+		}
+		return fakeXTest(xu, kcs[0], true)
+	}
+	if err := fakeXTest(xu, kcs[0], false); err != nil {
+		return err
+	}
+	for i := len(modCodes) - 1; i >= 0; i-- {
+		if err := fakeXTest(xu, modCodes[i], false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fakeXTest sends a synthetic key event with the XTEST extension.
+func fakeXTest(xu *xgbutil.XUtil, code xproto.Keycode, press bool) error {
+	evtype := byte(xproto.KeyRelease)
+	if press {
+		evtype = xproto.KeyPress
+	}
+	err := xtest.FakeInputChecked(xu.Conn(), evtype, byte(code), 0,
+		xproto.WindowNone, 0, 0, 0).Check()
+	if err != nil {
+		return fmt.Errorf("FakeInput: Could not send key event for keycode "+
+			"%d: %s", code, err)
+	}
+	return nil
+}
+
+// fakeSendEvent sends a synthetic key event to the window with the input
+// focus with SendEvent.
+func fakeSendEvent(xu *xgbutil.XUtil, mods uint16, code xproto.Keycode,
+	press bool) error {
+
+	focus, err := xproto.GetInputFocus(xu.Conn()).Reply()
+	if err != nil {
+		return fmt.Errorf("FakeInput: Could not get the input focus: %s", err)
+	}
+	win := focus.Focus
+	if win == xproto.InputFocusPointerRoot || win == xproto.InputFocusNone {
+		win = xu.RootWin()
+	}
+
+	ev := xproto.KeyPressEvent{
+		Detail:     code,
+		Time:       xu.TimeGet(),
+		Root:       xu.RootWin(),
+		Event:      win,
+		State:      mods,
+		SameScreen: true,
+	}
+	var evBytes []byte
+	evMask := uint32(xproto.EventMaskKeyPress)
+	if press {
+		evBytes = ev.Bytes()
+	} else {
+		evBytes = xproto.KeyReleaseEvent(ev).Bytes()
+		evMask = xproto.EventMaskKeyRelease
+	}
+
+	err = xproto.SendEventChecked(xu.Conn(), true, win, evMask,
+		string(evBytes)).Check()
+	if err != nil {
+		return fmt.Errorf("FakeInput: Could not send key event to window "+
+			"%x: %s", win, err)
+	}
+	return nil
+}
+
+// modKeycode returns the first keycode mapped to the modifier 'mod', or 0 if
+// there isn't one.
+func modKeycode(xu *xgbutil.XUtil, mod uint16) xproto.Keycode {
+	modMap := ModMapGet(xu)
+	per := int(modMap.KeycodesPerModifier)
+	for i, m := range Modifiers {
+		if m != mod || (i+1)*per > len(modMap.Keycodes) {
+			continue
+		}
+		for _, code := range modMap.Keycodes[i*per : (i+1)*per] {
+			if code != 0 {
+				return code
+			}
+		}
+	}
+	return 0
+}
@@ -27,7 +27,7 @@ var (
 // i.e., update state of the world on a MappingNotify.
 func Initialize(xu *xgbutil.XUtil) {
 	// Listen to mapping notify events
-	xevent.MappingNotifyFun(updateMaps).Connect(xu, xevent.NoWindow)
+	xevent.MappingNotifyFun(UpdateMapping).Connect(xu, xevent.NoWindow)
 
 	// Give us an initial mapping state...
 	keyMap, modMap := MapsGet(xu)
@@ -35,11 +35,15 @@ func Initialize(xu *xgbutil.XUtil) {
 	ModMapSet(xu, modMap)
 }
 
-// updateMaps runs in response to MappingNotify events.
+// UpdateMapping runs in response to MappingNotify events.
 // It is responsible for making sure our view of the world's keyboard
 // and modifier maps is correct. (Pointer mappings should be handled in
 // a similar callback in the mousebind package.)
-func updateMaps(xu *xgbutil.XUtil, e xevent.MappingNotifyEvent) {
+// When the keyboard mapping changes, every key binding is ungrabbed and
+// then bound again using the new keycodes.
+// Initialize connects UpdateMapping to MappingNotify events automatically.
+// It is exported for use by callers that process events without xevent.Main.
+func UpdateMapping(xu *xgbutil.XUtil, e xevent.MappingNotifyEvent) {
 	keyMap, modMap := MapsGet(xu)
 
 	// So we used to go through the old mapping and the new mapping and pick
@@ -49,10 +53,12 @@ func updateMaps(xu *xgbutil.XUtil, e xevent.MappingNotifyEvent) {
 	// based on the initial key strings.
 	if e.Request == xproto.MappingKeyboard {
 		// We must ungrab everything first, in case two keys are being swapped.
+		// The bindings aren't detached, since that would also remove the
+		// handlers attached by owned bindings (i.e., chords), which are
+		// bound again below.
 		keys := keyKeys(xu)
 		for _, key := range keys {
 			Ungrab(xu, key.Win, key.Mod, key.Code)
-			detach(xu, key.Evtype, key.Win)
 		}
 
 		// Wipe the slate clean.
@@ -168,6 +174,32 @@ func ParseString(
 	return mods, kcs, nil
 }
 
+// keysymString is the inverse of ParseString. It converts a (modifiers,
+// keysym) tuple into a key string like 'mod4-shift-Return'.
+// Key bindings are always stored as key strings, so that they can be bound
+// again when the keyboard mapping changes.
+func keysymString(mods uint16, keysym xproto.Keysym) (string, error) {
+	symStr, ok := strKeysyms[keysym]
+	if !ok {
+		return "", fmt.Errorf("Could not find a name for the keysym '%x'. "+
+			"Key binding failed.", keysym)
+	}
+
+	parts := make([]string, 0, 3)
+	for i, mod := range Modifiers {
+		if mods&mod == 0 {
+			continue
+		}
+		if mod == xproto.ModMaskAny {
+			parts = append(parts, "any")
+		} else {
+			parts = append(parts, NiceModifiers[i])
+		}
+	}
+	parts = append(parts, symStr)
+	return strings.Join(parts, "-"), nil
+}
+
 // StrToKeycodes is a wrapper around keycodesGet meant to make our search
 // a bit more flexible if needed. (i.e., case-insensitive)
 func StrToKeycodes(xu *xgbutil.XUtil, str string) []xproto.Keycode {
@@ -276,6 +308,34 @@ func ModGet(xu *xgbutil.XUtil, keycode xproto.Keycode) uint16 {
 	return 0
 }
 
+// ModifierFor finds the modifier that the key with the keysym 'keysymName'
+// (i.e., "Super_L" or "Alt_L") is attached to in the current modifier map.
+// This is useful because which of mod1 through mod5 is "the Alt key" or "the
+// Super key" depends on the user's keyboard configuration. (Super is
+// usually, but not always, mod4.)
+// An error is returned if the keysym doesn't exist, isn't mapped to any key,
+// or isn't attached to a modifier.
+// keybind.Initialize MUST have been called before using this function.
+func ModifierFor(xu *xgbutil.XUtil, keysymName string) (uint16, error) {
+	sym, ok := keysyms[keysymName]
+	if !ok {
+		return 0, fmt.Errorf("ModifierFor: Unknown keysym '%s'.", keysymName)
+	}
+
+	keycodes := keycodesGet(xu, sym)
+	if len(keycodes) == 0 {
+		return 0, fmt.Errorf("ModifierFor: No key is mapped to the keysym "+
+			"'%s'.", keysymName)
+	}
+	for _, keycode := range keycodes {
+		if mod := ModGet(xu, keycode); mod != 0 {
+			return mod, nil
+		}
+	}
+	return 0, fmt.Errorf("ModifierFor: The keysym '%s' is not attached to "+
+		"any modifier.", keysymName)
+}
+
 // Grab grabs a key with mods on a particular window.
 // This will also grab all combinations of modifiers found in xevent.IgnoreMods.
 func Grab(xu *xgbutil.XUtil, win xproto.Window,
@@ -317,10 +377,13 @@ func Ungrab(xu *xgbutil.XUtil, win xproto.Window,
 }
 
 // GrabKeyboard grabs the entire keyboard.
-// Returns whether GrabStatus is successful and an error if one is reported by
-// XGB. It is possible to not get an error and the grab to be unsuccessful.
+// An error is returned if XGB reports one, or if the grab is unsuccessful.
+// In the latter case, the error names the grab status. (i.e., AlreadyGrabbed
+// when another client has the keyboard grabbed.)
 // The purpose of 'win' is that after a grab is successful, ALL Key*Events will
 // be sent to that window. Make sure you have a callback attached :-)
+// This is useful for reading input that no other client should see, like a
+// password. Use UngrabKeyboard to release the grab.
 func GrabKeyboard(xu *xgbutil.XUtil, win xproto.Window) error {
 	reply, err := xproto.GrabKeyboard(xu.Conn(), false, win, 0,
 		xproto.GrabModeAsync, xproto.GrabModeAsync).Reply()
@@ -384,3 +447,57 @@ func DummyGrab(xu *xgbutil.XUtil) error {
 func DummyUngrab(xu *xgbutil.XUtil) {
 	SmartUngrab(xu)
 }
+
+// KeyMapString returns a human readable dump of the current keyboard mapping
+// and modifier mapping, in the same format as 'xmodmap -pke' and
+// 'xmodmap -pm'. This is useful for finding out why a key binding doesn't
+// match, and for including in bug reports.
+// keybind.Initialize MUST have been called before using this function.
+func KeyMapString(xu *xgbutil.XUtil) string {
+	var buf strings.Builder
+	keyMap, modMap := KeyMapGet(xu), ModMapGet(xu)
+	min, max := minMaxKeycodeGet(xu)
+	per := int(keyMap.KeysymsPerKeycode)
+
+	for code := int(min); code <= int(max); code++ {
+		syms := keyMap.Keysyms[(code-int(min))*per : (code-int(min)+1)*per]
+		for len(syms) > 0 && syms[len(syms)-1] == 0 {
+			syms = syms[:len(syms)-1]
+		}
+
+		fmt.Fprintf(&buf, "keycode %3d =", code)
+		for _, sym := range syms {
+			fmt.Fprintf(&buf, " %s", keysymName(sym))
+		}
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString("\n")
+	perMod := int(modMap.KeycodesPerModifier)
+	for i, name := range NiceModifiers[:8] {
+		fmt.Fprintf(&buf, "%-10s", name)
+		sep := ""
+		for _, code := range modMap.Keycodes[i*perMod : (i+1)*perMod] {
+			if code == 0 {
+				continue
+			}
+			fmt.Fprintf(&buf, "%s  %s (0x%x)", sep,
+				keysymName(KeysymGet(xu, code, 0)), code)
+			sep = ","
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// keysymName returns the name of a keysym as it is written in
+// keybind/keysymdef.go, or its value in hexadecimal if it has no name.
+func keysymName(keysym xproto.Keysym) string {
+	if keysym == 0 {
+		return "NoSymbol"
+	}
+	if name, ok := strKeysyms[keysym]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", keysym)
+}
@@ -0,0 +1,170 @@
+package keybind_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/keybind"
+	"github.com/jezek/xgbutil/xevent"
+)
+
+// newKeybindMock returns a mock XUtil whose keyboard mapping has one keysym
+// for each keycode, as given by 'keymap'. keybind.Initialize has been called.
+func newKeybindMock(t *testing.T,
+	keymap map[xproto.Keycode]xproto.Keysym) (*xgbutil.XUtil, *xgbutil.Mock) {
+
+	xu, m, err := xgbutil.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keymapSet(xu, m, keymap)
+	keybind.Initialize(xu)
+	return xu, m
+}
+
+// keymapSet makes the mock report 'keymap' as its keyboard mapping. The
+// mapping is only read again after a MappingNotify event.
+func keymapSet(xu *xgbutil.XUtil, m *xgbutil.Mock,
+	keymap map[xproto.Keycode]xproto.Keysym) {
+
+	min, max := xu.Setup().MinKeycode, xu.Setup().MaxKeycode
+	m.ReplySet(101, func(req xgbutil.MockRequest) []byte {
+		reply := make([]byte, 32+4*(int(max-min)+1))
+		reply[1] = 1 // keysyms per keycode
+		for kc, sym := range keymap {
+			xgb.Put32(reply[32+4*int(kc-min):], uint32(sym))
+		}
+		return reply
+	})
+}
+
+// remap changes the keyboard mapping and tells keybind about it.
+func remap(xu *xgbutil.XUtil, m *xgbutil.Mock,
+	keymap map[xproto.Keycode]xproto.Keysym) {
+
+	keymapSet(xu, m, keymap)
+	xevent.Dispatch(xu, xproto.MappingNotifyEvent{
+		Request: xproto.MappingKeyboard,
+	})
+}
+
+func TestChordAfterMappingNotify(t *testing.T) {
+	xu, m := newKeybindMock(t, map[xproto.Keycode]xproto.Keysym{
+		38: 'a', 39: 's',
+	})
+	defer xu.Conn().Close()
+
+	win := xu.RootWin()
+	chords := 0
+	err := keybind.KeyChordFun(
+		func(xu *xgbutil.XUtil, ev xevent.KeyPressEvent) {
+			chords++
+		}).Connect(xu, win, []string{"a", "s"}, time.Second, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chord := func(first, second xproto.Keycode, tm xproto.Timestamp) {
+		xevent.Dispatch(xu, xproto.KeyPressEvent{
+			Event: win, Detail: first, Time: tm})
+		xevent.Dispatch(xu, xproto.KeyPressEvent{
+			Event: win, Detail: second, Time: tm + 10})
+	}
+
+	chord(38, 39, 100)
+	if chords != 1 {
+		t.Fatalf("Expected 1 chord, but got %d.", chords)
+	}
+
+	remap(xu, m, map[xproto.Keycode]xproto.Keysym{52: 'a', 53: 's'})
+	chord(52, 53, 200)
+	if chords != 2 {
+		t.Fatalf("Expected 2 chords after a MappingNotify, but got %d.",
+			chords)
+	}
+}
+
+func TestNoRepeatAfterMappingNotify(t *testing.T) {
+	xu, m := newKeybindMock(t, map[xproto.Keycode]xproto.Keysym{38: 'a'})
+	defer xu.Conn().Close()
+
+	win := xu.RootWin()
+	presses := 0
+	err := keybind.KeyPressFun(
+		func(xu *xgbutil.XUtil, ev xevent.KeyPressEvent) {
+			presses++
+		}).ConnectNoRepeat(xu, win, "a", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tm := xproto.Timestamp(100)
+	cycle := func(kc xproto.Keycode) {
+		xevent.Dispatch(xu, xproto.KeyPressEvent{
+			Event: win, Detail: kc, Time: tm})
+		xevent.Dispatch(xu, xproto.KeyReleaseEvent{
+			Event: win, Detail: kc, Time: tm + 10})
+		tm += 100
+	}
+
+	cycle(38)
+	cycle(38)
+	if presses != 2 {
+		t.Fatalf("Expected 2 presses, but got %d.", presses)
+	}
+
+	remap(xu, m, map[xproto.Keycode]xproto.Keysym{38: 'a'})
+	cycle(38)
+	cycle(38)
+	if presses != 4 {
+		t.Fatalf("Expected 4 presses after a MappingNotify, but got %d.",
+			presses)
+	}
+}
+
+func TestModTapAfterMappingNotify(t *testing.T) {
+	xu, m := newKeybindMock(t, map[xproto.Keycode]xproto.Keysym{
+		38: 'a', 133: 0xffeb, // Super_L
+	})
+	defer xu.Conn().Close()
+
+	win := xu.RootWin()
+	taps := 0
+	err := keybind.ModTapFun(
+		func(xu *xgbutil.XUtil, ev xevent.KeyReleaseEvent) {
+			taps++
+		}).Connect(xu, win, "Super_L", time.Second, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tm := xproto.Timestamp(100)
+	tap := func(kc xproto.Keycode, others ...xproto.Keycode) {
+		xevent.Dispatch(xu, xproto.KeyPressEvent{
+			Event: win, Detail: kc, Time: tm})
+		for _, other := range others {
+			xevent.Dispatch(xu, xproto.KeyPressEvent{
+				Event: win, Detail: other, Time: tm + 5})
+		}
+		xevent.Dispatch(xu, xproto.KeyReleaseEvent{
+			Event: win, Detail: kc, Time: tm + 10})
+		tm += 100
+	}
+
+	tap(133)
+	tap(133, 38)
+	if taps != 1 {
+		t.Fatalf("Expected 1 tap, but got %d.", taps)
+	}
+
+	remap(xu, m, map[xproto.Keycode]xproto.Keysym{38: 'a', 134: 0xffeb})
+	tap(134)
+	tap(134, 38)
+	if taps != 2 {
+		t.Fatalf("Expected 2 taps after a MappingNotify, but got %d.", taps)
+	}
+}
@@ -0,0 +1,121 @@
+package keybind
+
+import (
+	"time"
+
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xevent"
+)
+
+// ModTapFun represents a function that is called when a modifier key is
+// tapped. That is, pressed and released on its own. (i.e., tapping the Super
+// key to open a launcher.)
+type ModTapFun xevent.KeyReleaseFun
+
+// Connect attaches the tap callback to the modifier key named by 'keyStr'
+// (i.e., "Super_L") on the window provided. The callback is run with the
+// KeyRelease event of the modifier key, but only if no other key or mouse
+// button was pressed while it was held down, and if it was released within
+// 'timeout' of being pressed.
+//
+// When 'grab' is true, the keyboard is grabbed by 'win' for as long as the
+// modifier key is held down. (This is how other key presses are detected.)
+// Note that mouse button presses can only be detected if they are reported
+// to 'win'.
+// The handlers attached to watch other key and button presses are removed
+// along with the binding by Detach, DetachPress or DetachPressString.
+func (callback ModTapFun) Connect(xu *xgbutil.XUtil, win xproto.Window,
+	keyStr string, timeout time.Duration, grab bool) error {
+
+	mt := &modTap{}
+	handles := []*xevent.Handle{
+		xevent.ConnectHandle(xu, xevent.KeyPress, win, xevent.KeyPressFun(
+			func(xu *xgbutil.XUtil, ev xevent.KeyPressEvent) {
+				if !mt.isKey(ev.Detail) {
+					mt.interrupt()
+				}
+			})),
+		xevent.ConnectHandle(xu, xevent.ButtonPress, win,
+			xevent.ButtonPressFun(
+				func(xu *xgbutil.XUtil, ev xevent.ButtonPressEvent) {
+					mt.interrupt()
+				})),
+		xevent.ConnectHandle(xu, xevent.KeyRelease, win,
+			xevent.KeyReleaseFun(
+				func(xu *xgbutil.XUtil, ev xevent.KeyReleaseEvent) {
+					if mt.isKey(ev.Detail) && mt.release(ev, timeout) {
+						callback(xu, ev)
+					}
+				})),
+	}
+
+	owned := &ownedKeyPress{
+		KeyPressFun: func(xu *xgbutil.XUtil, ev xevent.KeyPressEvent) {
+			mt.press(ev)
+		},
+		detach: func(xu *xgbutil.XUtil) {
+			for _, h := range handles {
+				xevent.DetachHandle(xu, h)
+			}
+		},
+	}
+	err := connect(xu, owned, xevent.KeyPress, win, keyStr, grab, false)
+	if err != nil {
+		owned.detach(xu)
+		return err
+	}
+	return nil
+}
+
+// modTap keeps track of the state of a single tap binding.
+// The keycode of the modifier key is recorded when it is pressed, rather than
+// when the binding is connected, since it changes when the keyboard mapping
+// does.
+type modTap struct {
+	keycode     xproto.Keycode
+	held        bool
+	interrupted bool
+	pressed     xproto.Timestamp
+}
+
+// isKey returns true if the keycode is that of the modifier key being held
+// down.
+func (mt *modTap) isKey(keycode xproto.Keycode) bool {
+	return mt.held && keycode == mt.keycode
+}
+
+// press records a press of the modifier key. Auto-repeated presses are
+// ignored.
+func (mt *modTap) press(ev xevent.KeyPressEvent) {
+	if mt.held {
+		return
+	}
+	mt.held = true
+	mt.keycode = ev.Detail
+	mt.interrupted = false
+	mt.pressed = ev.Time
+}
+
+// interrupt records that something else was pressed while the modifier key
+// was held down.
+func (mt *modTap) interrupt() {
+	if mt.held {
+		mt.interrupted = true
+	}
+}
+
+// release records a release of the modifier key, and returns true if it
+// completed a tap.
+func (mt *modTap) release(ev xevent.KeyReleaseEvent,
+	timeout time.Duration) bool {
+
+	if !mt.held {
+		return false
+	}
+	mt.held = false
+
+	elapsed := time.Duration(ev.Time-mt.pressed) * time.Millisecond
+	return !mt.interrupted && elapsed <= timeout
+}
@@ -0,0 +1,87 @@
+package keybind
+
+import (
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xevent"
+)
+
+// ConnectNoRepeat is the same as Connect, except that key presses generated
+// by auto-repeat are suppressed. Namely, the callback is run only once for
+// each physical press of the key binding.
+//
+// When a key is held down, X normally sends a KeyRelease/KeyPress pair with
+// the same timestamp for each repeat. If the XKB "detectable auto-repeat"
+// option is enabled, only KeyPress events are sent instead. Both cases are
+// detected by watching the KeyRelease events on 'win'. If 'grab' is false,
+// KeyRelease events are added to the event mask of 'win'. (With a grab, they
+// are always reported.)
+// The KeyRelease handler is removed along with the binding by Detach,
+// DetachPress or DetachPressString.
+func (callback KeyPressFun) ConnectNoRepeat(xu *xgbutil.XUtil,
+	win xproto.Window, keyStr string, grab bool) error {
+
+	if !grab {
+		err := xevent.SelectInputAdd(xu, win, xproto.EventMaskKeyRelease)
+		if err != nil {
+			return err
+		}
+	}
+
+	nr := &noRepeat{
+		down:     make(map[xproto.Keycode]bool),
+		released: make(map[xproto.Keycode]xproto.Timestamp),
+	}
+	release := xevent.ConnectHandle(xu, xevent.KeyRelease, win,
+		xevent.KeyReleaseFun(
+			func(xu *xgbutil.XUtil, ev xevent.KeyReleaseEvent) {
+				nr.release(ev)
+			}))
+
+	owned := &ownedKeyPress{
+		KeyPressFun: func(xu *xgbutil.XUtil, ev xevent.KeyPressEvent) {
+			if nr.press(ev) {
+				callback(xu, ev)
+			}
+		},
+		detach: func(xu *xgbutil.XUtil) {
+			xevent.DetachHandle(xu, release)
+		},
+	}
+	err := connect(xu, owned, xevent.KeyPress, win, keyStr, grab, false)
+	if err != nil {
+		owned.detach(xu)
+		return err
+	}
+	return nil
+}
+
+// noRepeat keeps track of which keys are physically held down for a single
+// ConnectNoRepeat binding.
+type noRepeat struct {
+	down     map[xproto.Keycode]bool
+	released map[xproto.Keycode]xproto.Timestamp
+}
+
+// press records a key press and returns true if it is a physical press
+// rather than an auto-repeat.
+func (nr *noRepeat) press(ev xevent.KeyPressEvent) bool {
+	if nr.down[ev.Detail] {
+		// No release since the last press: detectable auto-repeat.
+		return false
+	}
+	nr.down[ev.Detail] = true
+
+	// A release with the same timestamp as this press is synthetic.
+	if t, ok := nr.released[ev.Detail]; ok && t == ev.Time {
+		return false
+	}
+	return true
+}
+
+// release records a key release.
+func (nr *noRepeat) release(ev xevent.KeyReleaseEvent) {
+	nr.down[ev.Detail] = false
+	nr.released[ev.Detail] = ev.Time
+}
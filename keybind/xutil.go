@@ -13,6 +13,7 @@ import (
 	"github.com/jezek/xgb/xproto"
 
 	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xevent"
 )
 
 // attachKeyBindCallback associates an (event, window, mods, keycode)
@@ -54,6 +55,24 @@ func addKeyString(xu *xgbutil.XUtil, callback xgbutil.CallbackKey,
 	xu.Keystrings = append(xu.Keystrings, k)
 }
 
+// removeKeyString removes every key binding string in XUtil.Keystrings
+// matching the event type, window and key string given.
+func removeKeyString(xu *xgbutil.XUtil, evtype int, win xproto.Window,
+	keyStr string) {
+
+	xu.KeybindsLck.Lock()
+	defer xu.KeybindsLck.Unlock()
+
+	kept := make([]xgbutil.KeyString, 0, len(xu.Keystrings))
+	for _, k := range xu.Keystrings {
+		if k.Evtype == evtype && k.Win == win && k.Str == keyStr {
+			continue
+		}
+		kept = append(kept, k)
+	}
+	xu.Keystrings = kept
+}
+
 // keyBindKeys returns a copy of all the keys in the 'keybinds' map.
 func keyKeys(xu *xgbutil.XUtil) []xgbutil.KeyKey {
 	xu.KeybindsLck.RLock()
@@ -115,18 +134,40 @@ func connectedKeyBind(xu *xgbutil.XUtil, evtype int, win xproto.Window) bool {
 // This is exported for use in the keybind package. It should not be used.
 // To detach a window from a key binding callbacks, please use keybind.Detach.
 // (This method will issue an Ungrab requests, while keybind.Detach will.)
-func detachKeyBindWindow(xu *xgbutil.XUtil, evtype int, win xproto.Window) {
+// The callbacks removed are returned.
+func detachKeyBindWindow(xu *xgbutil.XUtil, evtype int,
+	win xproto.Window) []xgbutil.CallbackKey {
+
 	xu.KeybindsLck.Lock()
 	defer xu.KeybindsLck.Unlock()
 
 	// Since we can't create a full key, loop through all key binds
 	// and check if evtype and window match.
+	removed := make([]xgbutil.CallbackKey, 0)
 	for key, _ := range xu.Keybinds {
 		if key.Evtype == evtype && key.Win == win {
+			removed = append(removed, xu.Keybinds[key]...)
 			xu.Keygrabs[key] -= len(xu.Keybinds[key])
 			delete(xu.Keybinds, key)
 		}
 	}
+	return removed
+}
+
+// detachKeyBindKey removes all callbacks associated with a particular
+// event/window/mods/keycode combination.
+// Also decrements the counter in the corresponding 'keygrabs' map
+// appropriately. The callbacks removed are returned.
+func detachKeyBindKey(xu *xgbutil.XUtil,
+	key xgbutil.KeyKey) []xgbutil.CallbackKey {
+
+	xu.KeybindsLck.Lock()
+	defer xu.KeybindsLck.Unlock()
+
+	removed := xu.Keybinds[key]
+	xu.Keygrabs[key] -= len(xu.Keybinds[key])
+	delete(xu.Keybinds, key)
+	return removed
 }
 
 // keyBindGrabs returns the number of grabs on a particular
@@ -145,6 +186,17 @@ func keyBindGrabs(xu *xgbutil.XUtil, evtype int, win xproto.Window, mods uint16,
 	return xu.Keygrabs[key] // returns 0 if key does not exist
 }
 
+// keyGrabs returns the number of grabs on a particular window/mods/keycode
+// combination for both key press and key release events. Since X has a
+// single passive grab for both, the key is grabbed when this goes up from
+// zero and ungrabbed when it drops back to zero.
+func keyGrabs(xu *xgbutil.XUtil, win xproto.Window, mods uint16,
+	keycode xproto.Keycode) int {
+
+	return keyBindGrabs(xu, xevent.KeyPress, win, mods, keycode) +
+		keyBindGrabs(xu, xevent.KeyRelease, win, mods, keycode)
+}
+
 // KeyMapGet accessor.
 func KeyMapGet(xu *xgbutil.XUtil) *xgbutil.KeyboardMapping {
 	return xu.Keymap
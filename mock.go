@@ -0,0 +1,494 @@
+package xgbutil
+
+/*
+mock.go contains NewMock, which creates an XUtil connected to a fake X server
+that runs in memory. It is meant for testing code that uses xgbutil without a
+real X server.
+*/
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// MockRequest is a request that was sent to a Mock X server.
+type MockRequest struct {
+	// Opcode is the major opcode of the request. (i.e., 18 for
+	// ChangeProperty.)
+	Opcode byte
+
+	// Sequence is the sequence number of the request, starting at 1.
+	Sequence uint16
+
+	// Data is the complete request, including its 4 byte header.
+	Data []byte
+}
+
+// MockReplyFun is the type of function that produces the reply to a request
+// sent to a Mock X server. See Mock.ReplySet.
+type MockReplyFun func(req MockRequest) []byte
+
+// Mock is a fake X server running in memory, created by NewMock. It records
+// every request it receives, and answers them as follows:
+//
+//   - InternAtom and GetAtomName use an atom table, which starts out with the
+//     atoms predefined by the X protocol.
+//   - ChangeProperty, DeleteProperty, GetProperty and ListProperties use a
+//     property store for any window id. (No PropertyNotify events are sent.)
+//   - QueryExtension always reports that the extension is missing.
+//   - Every other core request that has a reply gets a reply of zeroes (of
+//     the right size, with empty lists), unless a reply function has been
+//     set for its opcode with ReplySet.
+//
+// Nothing else is emulated: there are no windows, so requests like
+// CreateWindow or MapWindow are only recorded, and errors are never sent
+// except for GetAtomName with an unknown atom.
+type Mock struct {
+	conn     net.Conn
+	writeLck sync.Mutex
+
+	lck       sync.Mutex
+	requests  []MockRequest
+	replies   map[byte]MockReplyFun
+	atoms     map[string]xproto.Atom
+	atomNames map[xproto.Atom]string
+	props     map[xproto.Window]map[xproto.Atom]mockProp
+}
+
+// mockProp is the value of a property stored by a Mock X server.
+type mockProp struct {
+	typ    xproto.Atom
+	format byte
+	data   []byte
+}
+
+// mockRoot is the id of the root window of a Mock X server.
+const mockRoot = xproto.Window(0x100)
+
+// mockReplySizes are the opcodes of the core requests that have replies,
+// along with the size of the fixed part of their replies. (Most are 32
+// bytes, but some are longer.) Lists in the default replies are empty.
+var mockReplySizes = map[byte]int{
+	3: 44, 14: 32, 15: 32, 16: 32, 17: 32, 20: 32, 21: 32, 23: 32, 26: 32,
+	31: 32, 38: 32, 39: 32, 40: 32, 43: 32, 44: 40, 47: 60, 48: 32, 49: 32,
+	50: 60, 52: 32, 73: 32, 83: 32, 84: 32, 85: 32, 86: 32, 87: 32, 91: 32,
+	92: 32, 97: 32, 98: 32, 99: 32, 101: 32, 103: 52, 106: 32, 108: 32,
+	110: 32, 116: 32, 117: 32, 118: 32, 119: 32,
+}
+
+// mockPredefinedAtoms are the atoms predefined by the X protocol, starting
+// at atom 1.
+var mockPredefinedAtoms = []string{
+	"PRIMARY", "SECONDARY", "ARC", "ATOM", "BITMAP", "CARDINAL", "COLORMAP",
+	"CURSOR", "CUT_BUFFER0", "CUT_BUFFER1", "CUT_BUFFER2", "CUT_BUFFER3",
+	"CUT_BUFFER4", "CUT_BUFFER5", "CUT_BUFFER6", "CUT_BUFFER7", "DRAWABLE",
+	"FONT", "INTEGER", "PIXMAP", "POINT", "RECTANGLE", "RESOURCE_MANAGER",
+	"RGB_COLOR_MAP", "RGB_BEST_MAP", "RGB_BLUE_MAP", "RGB_DEFAULT_MAP",
+	"RGB_GRAY_MAP", "RGB_GREEN_MAP", "RGB_RED_MAP", "STRING", "VISUALID",
+	"WINDOW", "WM_COMMAND", "WM_HINTS", "WM_CLIENT_MACHINE", "WM_ICON_NAME",
+	"WM_ICON_SIZE", "WM_NAME", "WM_NORMAL_HINTS", "WM_SIZE_HINTS",
+	"WM_ZOOM_HINTS", "MIN_SPACE", "NORM_SPACE", "MAX_SPACE", "END_SPACE",
+	"SUPERSCRIPT_X", "SUPERSCRIPT_Y", "SUBSCRIPT_X", "SUBSCRIPT_Y",
+	"UNDERLINE_POSITION", "UNDERLINE_THICKNESS", "STRIKEOUT_ASCENT",
+	"STRIKEOUT_DESCENT", "ITALIC_ANGLE", "X_HEIGHT", "QUAD_WIDTH", "WEIGHT",
+	"POINT_SIZE", "RESOLUTION", "COPYRIGHT", "NOTICE", "FONT_NAME",
+	"FAMILY_NAME", "FULL_NAME", "CAP_HEIGHT", "WM_CLASS", "WM_TRANSIENT_FOR",
+}
+
+// NewMock creates a new XUtil connected to a fake X server running in memory,
+// so that code using xgbutil can be tested without a real X server. The
+// returned Mock records the requests sent by the XUtil, answers the common
+// ones (see Mock), and can be used to send events to it.
+// The fake X server has a single 1920x1080 screen with a 24 bit TrueColor
+// visual, and no extensions. (So a warning about Xinerama is logged.)
+// Events sent with Mock.SendEvent are read by the main event loop like any
+// other event, and can also be processed without a main event loop with
+// xevent.Read and xevent.Dequeue, or built by hand and processed with
+// xevent.Dispatch.
+// Note that Reconnect cannot be used with a mock XUtil.
+func NewMock() (*XUtil, *Mock, error) {
+	client, server := net.Pipe()
+	m := &Mock{
+		conn:      server,
+		replies:   make(map[byte]MockReplyFun),
+		atoms:     make(map[string]xproto.Atom, len(mockPredefinedAtoms)),
+		atomNames: make(map[xproto.Atom]string, len(mockPredefinedAtoms)),
+		props:     make(map[xproto.Window]map[xproto.Atom]mockProp),
+	}
+	for i, name := range mockPredefinedAtoms {
+		m.atoms[name] = xproto.Atom(i + 1)
+		m.atomNames[xproto.Atom(i+1)] = name
+	}
+	go m.serve()
+
+	c, err := xgb.NewConnNet(client)
+	if err != nil {
+		server.Close()
+		return nil, nil, fmt.Errorf("NewMock: Could not connect to the fake "+
+			"X server: %s", err)
+	}
+	xu, err := NewConnXgb(c)
+	if err != nil {
+		c.Close()
+		return nil, nil, err
+	}
+	return xu, m, nil
+}
+
+// Requests returns every request received so far, in order.
+func (m *Mock) Requests() []MockRequest {
+	m.lck.Lock()
+	defer m.lck.Unlock()
+
+	reqs := make([]MockRequest, len(m.requests))
+	copy(reqs, m.requests)
+	return reqs
+}
+
+// ReplySet makes 'f' produce the reply to every request with the major
+// opcode 'opcode', replacing the default reply (if any). The reply returned
+// by 'f' must be at least as long as the fixed part of the reply, and is laid
+// out as described by the X protocol. Its reply code, sequence number and
+// length are filled in automatically, so 'f' only needs to set the rest of
+// the fields. If 'f' returns nil, the default reply of zeroes is sent.
+// Only set reply functions for requests that have replies, since a reply to
+// any other request confuses the connection.
+func (m *Mock) ReplySet(opcode byte, f MockReplyFun) {
+	m.lck.Lock()
+	defer m.lck.Unlock()
+
+	m.replies[opcode] = f
+}
+
+// SendEvent sends 'ev' to the XUtil, as if it was generated by the X server.
+func (m *Mock) SendEvent(ev xgb.Event) error {
+	return m.write(ev.Bytes())
+}
+
+// write sends a reply, error or event to the XUtil.
+func (m *Mock) write(buf []byte) error {
+	m.writeLck.Lock()
+	defer m.writeLck.Unlock()
+
+	_, err := m.conn.Write(buf)
+	return err
+}
+
+// serve runs the fake X server until the connection is closed.
+func (m *Mock) serve() {
+	defer m.conn.Close()
+
+	if err := m.handshake(); err != nil {
+		Logger.Printf("Mock: Connection setup failed: %s", err)
+		return
+	}
+
+	var seq uint16
+	for {
+		req, err := m.readRequest()
+		if err != nil {
+			return
+		}
+		seq++
+		req.Sequence = seq
+
+		m.lck.Lock()
+		m.requests = append(m.requests, req)
+		f := m.replies[req.Opcode]
+		m.lck.Unlock()
+
+		var reply []byte
+		switch {
+		case f != nil:
+			reply = f(req)
+			if reply == nil {
+				reply = make([]byte, mockReplySizes[req.Opcode])
+			}
+		case req.Opcode == 16:
+			reply = m.internAtom(req)
+		case req.Opcode == 17:
+			reply = m.getAtomName(req)
+			if reply == nil {
+				m.writeError(req, 5, xgb.Get32(req.Data[4:])) // BadAtom
+				continue
+			}
+		case req.Opcode == 18:
+			m.changeProperty(req)
+		case req.Opcode == 19:
+			m.deleteProperty(req)
+		case req.Opcode == 20:
+			reply = m.getProperty(req)
+		case req.Opcode == 21:
+			reply = m.listProperties(req)
+		case mockReplySizes[req.Opcode] > 0:
+			reply = make([]byte, mockReplySizes[req.Opcode])
+		}
+		if reply != nil {
+			m.writeReply(req, reply)
+		}
+	}
+}
+
+// handshake reads the connection setup request and answers it with the
+// setup information of the fake X server.
+func (m *Mock) handshake() error {
+	head := make([]byte, 12)
+	if _, err := io.ReadFull(m.conn, head); err != nil {
+		return err
+	}
+	authLen := xgb.Pad(int(xgb.Get16(head[6:]))) +
+		xgb.Pad(int(xgb.Get16(head[8:])))
+	if _, err := io.ReadFull(m.conn, make([]byte, authLen)); err != nil {
+		return err
+	}
+
+	vendor := "xgbutil mock"
+	setup := xproto.SetupInfo{
+		Status:                   1,
+		ProtocolMajorVersion:     11,
+		ReleaseNumber:            1,
+		ResourceIdBase:           0x00200000,
+		ResourceIdMask:           0x001fffff,
+		VendorLen:                uint16(len(vendor)),
+		MaximumRequestLength:     0xffff,
+		RootsLen:                 1,
+		PixmapFormatsLen:         1,
+		BitmapFormatScanlineUnit: 32,
+		BitmapFormatScanlinePad:  32,
+		MinKeycode:               8,
+		MaxKeycode:               255,
+		Vendor:                   vendor,
+		PixmapFormats: []xproto.Format{
+			{Depth: 24, BitsPerPixel: 32, ScanlinePad: 32},
+		},
+		Roots: []xproto.ScreenInfo{{
+			Root:                mockRoot,
+			DefaultColormap:     0x20,
+			WhitePixel:          0xffffff,
+			WidthInPixels:       1920,
+			HeightInPixels:      1080,
+			WidthInMillimeters:  508,
+			HeightInMillimeters: 286,
+			MinInstalledMaps:    1,
+			MaxInstalledMaps:    1,
+			RootVisual:          0x21,
+			RootDepth:           24,
+			AllowedDepthsLen:    1,
+			AllowedDepths: []xproto.DepthInfo{{
+				Depth:      24,
+				VisualsLen: 1,
+				Visuals: []xproto.VisualInfo{{
+					VisualId:        0x21,
+					Class:           xproto.VisualClassTrueColor,
+					BitsPerRgbValue: 8,
+					ColormapEntries: 256,
+					RedMask:         0xff0000,
+					GreenMask:       0x00ff00,
+					BlueMask:        0x0000ff,
+				}},
+			}},
+		}},
+	}
+	buf := setup.Bytes()
+	xgb.Put16(buf[6:], uint16((len(buf)-8)/4))
+	return m.write(buf)
+}
+
+// readRequest reads the next request from the connection.
+func (m *Mock) readRequest() (MockRequest, error) {
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(m.conn, head); err != nil {
+		return MockRequest{}, err
+	}
+	size := int(xgb.Get16(head[2:])) * 4
+	if size == 0 { // BIG-REQUESTS
+		ext := make([]byte, 4)
+		if _, err := io.ReadFull(m.conn, ext); err != nil {
+			return MockRequest{}, err
+		}
+		head = append(head, ext...)
+		size = int(xgb.Get32(ext)) * 4
+	}
+	if size < len(head) {
+		return MockRequest{}, fmt.Errorf("invalid request length %d", size)
+	}
+
+	data := make([]byte, size)
+	copy(data, head)
+	if _, err := io.ReadFull(m.conn, data[len(head):]); err != nil {
+		return MockRequest{}, err
+	}
+	return MockRequest{Opcode: head[0], Data: data}, nil
+}
+
+// writeReply fills in the header of a reply to 'req' and sends it.
+func (m *Mock) writeReply(req MockRequest, reply []byte) {
+	if len(reply) < 32 {
+		reply = append(reply, make([]byte, 32-len(reply))...)
+	}
+	if pad := xgb.Pad(len(reply)) - len(reply); pad > 0 {
+		reply = append(reply, make([]byte, pad)...)
+	}
+	reply[0] = 1
+	xgb.Put16(reply[2:], req.Sequence)
+	xgb.Put32(reply[4:], uint32((len(reply)-32)/4))
+	m.write(reply)
+}
+
+// writeError sends an error with the code 'code' in response to 'req'.
+func (m *Mock) writeError(req MockRequest, code byte, badValue uint32) {
+	buf := make([]byte, 32)
+	buf[1] = code
+	xgb.Put16(buf[2:], req.Sequence)
+	xgb.Put32(buf[4:], badValue)
+	buf[10] = req.Opcode
+	m.write(buf)
+}
+
+// internAtom answers an InternAtom request.
+func (m *Mock) internAtom(req MockRequest) []byte {
+	onlyIfExists := req.Data[1] != 0
+	name := string(req.Data[8 : 8+int(xgb.Get16(req.Data[4:]))])
+
+	m.lck.Lock()
+	defer m.lck.Unlock()
+
+	atom, ok := m.atoms[name]
+	if !ok && !onlyIfExists {
+		atom = xproto.Atom(len(m.atoms) + 1)
+		m.atoms[name] = atom
+		m.atomNames[atom] = name
+	}
+
+	reply := make([]byte, 32)
+	xgb.Put32(reply[8:], uint32(atom))
+	return reply
+}
+
+// getAtomName answers a GetAtomName request, or returns nil if the atom
+// doesn't exist.
+func (m *Mock) getAtomName(req MockRequest) []byte {
+	m.lck.Lock()
+	defer m.lck.Unlock()
+
+	name, ok := m.atomNames[xproto.Atom(xgb.Get32(req.Data[4:]))]
+	if !ok {
+		return nil
+	}
+
+	reply := make([]byte, 32+xgb.Pad(len(name)))
+	xgb.Put16(reply[8:], uint16(len(name)))
+	copy(reply[32:], name)
+	return reply
+}
+
+// changeProperty handles a ChangeProperty request.
+func (m *Mock) changeProperty(req MockRequest) {
+	mode := req.Data[1]
+	win := xproto.Window(xgb.Get32(req.Data[4:]))
+	atom := xproto.Atom(xgb.Get32(req.Data[8:]))
+	typ := xproto.Atom(xgb.Get32(req.Data[12:]))
+	format := req.Data[16]
+	size := int(xgb.Get32(req.Data[20:])) * int(format) / 8
+	data := make([]byte, size)
+	copy(data, req.Data[24:])
+
+	m.lck.Lock()
+	defer m.lck.Unlock()
+
+	if m.props[win] == nil {
+		m.props[win] = make(map[xproto.Atom]mockProp)
+	}
+	old, ok := m.props[win][atom]
+	if ok {
+		switch mode {
+		case xproto.PropModePrepend:
+			data = append(data, old.data...)
+		case xproto.PropModeAppend:
+			data = append(old.data, data...)
+		}
+	}
+	m.props[win][atom] = mockProp{typ: typ, format: format, data: data}
+}
+
+// deleteProperty handles a DeleteProperty request.
+func (m *Mock) deleteProperty(req MockRequest) {
+	win := xproto.Window(xgb.Get32(req.Data[4:]))
+	atom := xproto.Atom(xgb.Get32(req.Data[8:]))
+
+	m.lck.Lock()
+	defer m.lck.Unlock()
+
+	delete(m.props[win], atom)
+}
+
+// getProperty answers a GetProperty request.
+func (m *Mock) getProperty(req MockRequest) []byte {
+	del := req.Data[1] != 0
+	win := xproto.Window(xgb.Get32(req.Data[4:]))
+	atom := xproto.Atom(xgb.Get32(req.Data[8:]))
+	typ := xproto.Atom(xgb.Get32(req.Data[12:]))
+	offset := int(xgb.Get32(req.Data[16:])) * 4
+	length := int(xgb.Get32(req.Data[20:])) * 4
+
+	m.lck.Lock()
+	defer m.lck.Unlock()
+
+	prop, ok := m.props[win][atom]
+	if !ok {
+		return make([]byte, 32)
+	}
+	if typ != xproto.GetPropertyTypeAny && typ != prop.typ {
+		reply := make([]byte, 32)
+		reply[1] = prop.format
+		xgb.Put32(reply[8:], uint32(prop.typ))
+		xgb.Put32(reply[12:], uint32(len(prop.data)))
+		return reply
+	}
+
+	start := offset
+	if start > len(prop.data) {
+		start = len(prop.data)
+	}
+	end := len(prop.data)
+	if length < end-start {
+		end = start + length
+	}
+	value := prop.data[start:end]
+	after := len(prop.data) - end
+	if del && after == 0 {
+		delete(m.props[win], atom)
+	}
+
+	reply := make([]byte, 32+len(value))
+	reply[1] = prop.format
+	xgb.Put32(reply[8:], uint32(prop.typ))
+	xgb.Put32(reply[12:], uint32(after))
+	if prop.format > 0 {
+		xgb.Put32(reply[16:], uint32(len(value)*8/int(prop.format)))
+	}
+	copy(reply[32:], value)
+	return reply
+}
+
+// listProperties answers a ListProperties request.
+func (m *Mock) listProperties(req MockRequest) []byte {
+	win := xproto.Window(xgb.Get32(req.Data[4:]))
+
+	m.lck.Lock()
+	defer m.lck.Unlock()
+
+	reply := make([]byte, 32+4*len(m.props[win]))
+	xgb.Put16(reply[8:], uint16(len(m.props[win])))
+	i := 32
+	for atom := range m.props[win] {
+		xgb.Put32(reply[i:], uint32(atom))
+		i += 4
+	}
+	return reply
+}
@@ -0,0 +1,75 @@
+package xgbutil_test
+
+import (
+	"testing"
+
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xprop"
+)
+
+func TestMockProperties(t *testing.T) {
+	xu, _, err := xgbutil.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xu.Conn().Close()
+
+	err = xprop.ChangeProp32(xu, xu.RootWin(), "_TEST", "CARDINAL", 1, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := xprop.GetProperty(xu, xu.RootWin(), "_TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nums, err := xprop.PropValNums(reply, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nums) != 3 || nums[0] != 1 || nums[1] != 2 || nums[2] != 3 {
+		t.Fatalf("Expected [1 2 3], but got %v.", nums)
+	}
+}
+
+func TestMockDefaultReplies(t *testing.T) {
+	xu, m, err := xgbutil.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xu.Conn().Close()
+
+	// These replies are longer than 32 bytes.
+	_, err = xproto.GetWindowAttributes(xu.Conn(), xu.RootWin()).Reply()
+	if err != nil {
+		t.Fatalf("GetWindowAttributes: %s", err)
+	}
+	if _, err = xproto.QueryKeymap(xu.Conn()).Reply(); err != nil {
+		t.Fatalf("QueryKeymap: %s", err)
+	}
+	if _, err = xproto.GetKeyboardControl(xu.Conn()).Reply(); err != nil {
+		t.Fatalf("GetKeyboardControl: %s", err)
+	}
+
+	m.ReplySet(14, func(req xgbutil.MockRequest) []byte {
+		reply := make([]byte, 32)
+		reply[16], reply[18] = 200, 100 // width, height
+		return reply
+	})
+	geom, err := xproto.GetGeometry(xu.Conn(),
+		xproto.Drawable(xu.RootWin())).Reply()
+	if err != nil {
+		t.Fatalf("GetGeometry: %s", err)
+	}
+	if geom.Width != 200 || geom.Height != 100 {
+		t.Fatalf("Expected a 200x100 geometry, but got %dx%d.",
+			geom.Width, geom.Height)
+	}
+
+	reqs := m.Requests()
+	if last := reqs[len(reqs)-1]; last.Opcode != 14 {
+		t.Fatalf("Expected the last request to be GetGeometry, but got "+
+			"opcode %d.", last.Opcode)
+	}
+}
@@ -2,6 +2,7 @@ package mousebind
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/jezek/xgb/xproto"
 
@@ -9,10 +10,16 @@ import (
 	"github.com/jezek/xgbutil/xevent"
 )
 
+func init() {
+	xevent.DetachHook(Detach)
+}
+
 // connect is essentially 'Connect' for either ButtonPress or
 // ButtonRelease events.
+// If 'ignoreMods' is nil, then xevent.IgnoreMods is used.
 func connect(xu *xgbutil.XUtil, callback xgbutil.CallbackMouse, evtype int,
-	win xproto.Window, buttonStr string, sync bool, grab bool) error {
+	win xproto.Window, buttonStr string, sync bool, grab bool,
+	ignoreMods []uint16) error {
 
 	// Get the mods/button first
 	mods, button, err := ParseString(xu, buttonStr)
@@ -23,7 +30,11 @@ func connect(xu *xgbutil.XUtil, callback xgbutil.CallbackMouse, evtype int,
 	// Only do the grab if we haven't yet on this window.
 	// And if we WANT a grab...
 	if grab && mouseBindGrabs(xu, evtype, win, mods, button) == 0 {
-		err := GrabChecked(xu, win, mods, button, sync)
+		masks := ignoreMods
+		if masks == nil {
+			masks = xevent.IgnoreMods
+		}
+		err := grabChecked(xu, win, mods, button, sync, masks)
 		if err != nil {
 			// If a bad access, let's be nice and give a good error message.
 			switch err.(type) {
@@ -55,6 +66,10 @@ func connect(xu *xgbutil.XUtil, callback xgbutil.CallbackMouse, evtype int,
 
 	// Finally, attach the callback.
 	attachMouseBindCallback(xu, evtype, win, mods, button, callback)
+	if ignoreMods != nil {
+		mouseIgnoreModsSet(xu, xgbutil.MouseKey{evtype, win, mods, button},
+			ignoreMods)
+	}
 
 	return nil
 }
@@ -65,8 +80,16 @@ func connect(xu *xgbutil.XUtil, callback xgbutil.CallbackMouse, evtype int,
 func DeduceButtonInfo(state uint16,
 	detail xproto.Button) (uint16, xproto.Button) {
 
+	return deduceButtonInfo(state, detail, xevent.IgnoreMods)
+}
+
+// deduceButtonInfo is the same as DeduceButtonInfo, except the modifiers
+// to ignore are given explicitly.
+func deduceButtonInfo(state uint16, detail xproto.Button,
+	ignoreMods []uint16) (uint16, xproto.Button) {
+
 	mods, button := state, detail
-	for _, m := range xevent.IgnoreMods {
+	for _, m := range ignoreMods {
 		mods &= ^m
 	}
 
@@ -100,13 +123,60 @@ type ButtonPressFun xevent.ButtonPressFun
 func (callback ButtonPressFun) Connect(xu *xgbutil.XUtil, win xproto.Window,
 	buttonStr string, sync bool, grab bool) error {
 
-	return connect(xu, callback, xevent.ButtonPress, win, buttonStr, sync, grab)
+	return connect(xu, callback, xevent.ButtonPress, win, buttonStr,
+		sync, grab, nil)
+}
+
+// ConnectWithMasks is the same as Connect, except that the modifier masks
+// in 'ignoreMods' are used instead of xevent.IgnoreMods for this binding.
+// For example, passing []uint16{0} creates a binding that only fires when
+// neither num lock nor caps lock is enabled.
+// All callbacks connected to the same button sequence on the same window
+// share the masks of the most recent call to ConnectWithMasks.
+func (callback ButtonPressFun) ConnectWithMasks(xu *xgbutil.XUtil,
+	win xproto.Window, buttonStr string, sync bool, grab bool,
+	ignoreMods []uint16) error {
+
+	return connect(xu, callback, xevent.ButtonPress, win, buttonStr,
+		sync, grab, ignoreMods)
+}
+
+// ConnectReplay is the same as Connect, except it always issues a
+// synchronous passive grab and calls Replay after the callback returns.
+// This lets the callback respond to the button press while still sending it
+// on to child windows, without having to remember to call AllowEvents.
+func (callback ButtonPressFun) ConnectReplay(xu *xgbutil.XUtil,
+	win xproto.Window, buttonStr string) error {
+
+	return ButtonPressFun(
+		func(xu *xgbutil.XUtil, ev xevent.ButtonPressEvent) {
+			defer Replay(xu)
+			callback(xu, ev)
+		}).Connect(xu, win, buttonStr, true, true)
 }
 
 func (callback ButtonPressFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(xevent.ButtonPressEvent))
 }
 
+// ownedButtonPress is a button press binding that attaches handlers of its
+// own besides the binding itself. (i.e., to watch button releases.) 'detach'
+// removes them, and is run when the binding is detached.
+type ownedButtonPress struct {
+	ButtonPressFun
+	detach func(xu *xgbutil.XUtil)
+}
+
+// detachOwned removes the handlers attached by any owned bindings in 'cbs'.
+func detachOwned(xu *xgbutil.XUtil, cbs []xgbutil.CallbackMouse) {
+	for _, cb := range cbs {
+		if owned, ok := cb.(*ownedButtonPress); ok && owned.detach != nil {
+			owned.detach(xu)
+			owned.detach = nil
+		}
+	}
+}
+
 // ButtonReleaseFun represents a function that is called when a particular mouse
 // binding is fired.
 type ButtonReleaseFun xevent.ButtonReleaseFun
@@ -119,7 +189,18 @@ func (callback ButtonReleaseFun) Connect(xu *xgbutil.XUtil, win xproto.Window,
 	buttonStr string, sync bool, grab bool) error {
 
 	return connect(xu, callback, xevent.ButtonRelease, win, buttonStr,
-		sync, grab)
+		sync, grab, nil)
+}
+
+// ConnectWithMasks is the same as Connect, except that the modifier masks
+// in 'ignoreMods' are used instead of xevent.IgnoreMods for this binding.
+// See ButtonPressFun.ConnectWithMasks for more details.
+func (callback ButtonReleaseFun) ConnectWithMasks(xu *xgbutil.XUtil,
+	win xproto.Window, buttonStr string, sync bool, grab bool,
+	ignoreMods []uint16) error {
+
+	return connect(xu, callback, xevent.ButtonRelease, win, buttonStr,
+		sync, grab, ignoreMods)
 }
 
 func (callback ButtonReleaseFun) Run(xu *xgbutil.XUtil, event interface{}) {
@@ -129,9 +210,8 @@ func (callback ButtonReleaseFun) Run(xu *xgbutil.XUtil, event interface{}) {
 // runButtonPressCallbacks infers the window, button and modifiers from a
 // ButtonPressEvent and runs the corresponding callbacks.
 func runButtonPressCallbacks(xu *xgbutil.XUtil, ev xevent.ButtonPressEvent) {
-	mods, button := DeduceButtonInfo(ev.State, ev.Detail)
-
-	runMouseBindCallbacks(xu, ev, xevent.ButtonPress, ev.Event, mods, button)
+	runMouseBindCallbacks(xu, ev, xevent.ButtonPress, ev.Event,
+		ev.State, ev.Detail)
 }
 
 // runButtonReleaseCallbacks infers the window, keycode and modifiers from a
@@ -139,9 +219,31 @@ func runButtonPressCallbacks(xu *xgbutil.XUtil, ev xevent.ButtonPressEvent) {
 func runButtonReleaseCallbacks(xu *xgbutil.XUtil,
 	ev xevent.ButtonReleaseEvent) {
 
-	mods, button := DeduceButtonInfo(ev.State, ev.Detail)
+	runMouseBindCallbacks(xu, ev, xevent.ButtonRelease, ev.Event,
+		ev.State, ev.Detail)
+}
 
-	runMouseBindCallbacks(xu, ev, xevent.ButtonRelease, ev.Event, mods, button)
+// GrabbedButtons returns the button sequences that have handlers attached to
+// the provided window by this package, for both button press and button
+// release events. Each sequence is reported once, and the result is sorted.
+// The server is not queried, so this only reflects bindings made with
+// Connect (with or without a passive grab). It is meant to help debug mouse
+// bindings that don't seem to fire.
+func GrabbedButtons(xu *xgbutil.XUtil, win xproto.Window) []string {
+	seen := make(map[string]bool)
+	seqs := make([]string, 0)
+	for _, key := range mouseKeys(xu) {
+		if key.Win != win {
+			continue
+		}
+		seq := String(key.Mod, key.Button)
+		if !seen[seq] {
+			seen[seq] = true
+			seqs = append(seqs, seq)
+		}
+	}
+	sort.Strings(seqs)
+	return seqs
 }
 
 // Detach removes all handlers for all mouse events for the provided window id.
@@ -164,15 +266,55 @@ func DetachRelease(xu *xgbutil.XUtil, win xproto.Window) {
 	detach(xu, xevent.ButtonRelease, win)
 }
 
+// DetachPressString removes all handlers for button *press* events on the
+// provided window that are attached to the given button sequence. Handlers
+// for other button sequences on the same window are left intact.
+// A single ungrab request is issued for the sequence, regardless of how many
+// handlers were attached to it.
+func DetachPressString(xu *xgbutil.XUtil, win xproto.Window,
+	buttonStr string) error {
+
+	return detachString(xu, xevent.ButtonPress, win, buttonStr)
+}
+
+// DetachReleaseString is the same as DetachPressString, except it only
+// removes handlers for button *release* events.
+func DetachReleaseString(xu *xgbutil.XUtil, win xproto.Window,
+	buttonStr string) error {
+
+	return detachString(xu, xevent.ButtonRelease, win, buttonStr)
+}
+
+// detachString removes all handlers for the provided window, event type and
+// button sequence combination. An ungrab request is issued if the grab count
+// drops to zero.
+func detachString(xu *xgbutil.XUtil, evtype int, win xproto.Window,
+	buttonStr string) error {
+
+	mods, button, err := ParseString(xu, buttonStr)
+	if err != nil {
+		return err
+	}
+
+	key := xgbutil.MouseKey{evtype, win, mods, button}
+	detachOwned(xu, detachMouseBindKey(xu, key))
+	if mouseBindGrabs(xu, evtype, win, mods, button) == 0 {
+		ungrab(xu, win, mods, button, mouseIgnoreMods(xu, key))
+		mouseIgnoreModsDel(xu, key)
+	}
+	return nil
+}
+
 // detach removes all handlers for the provided window and event type
 // combination. This will also issue an ungrab request for each grab that
 // drops to zero.
 func detach(xu *xgbutil.XUtil, evtype int, win xproto.Window) {
 	mkeys := mouseKeys(xu)
-	detachMouseBindWindow(xu, evtype, win)
+	detachOwned(xu, detachMouseBindWindow(xu, evtype, win))
 	for _, key := range mkeys {
 		if mouseBindGrabs(xu, key.Evtype, key.Win, key.Mod, key.Button) == 0 {
-			Ungrab(xu, key.Win, key.Mod, key.Button)
+			ungrab(xu, key.Win, key.Mod, key.Button, mouseIgnoreMods(xu, key))
+			mouseIgnoreModsDel(xu, key)
 		}
 	}
 }
@@ -0,0 +1,89 @@
+package mousebind
+
+import (
+	"time"
+
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xevent"
+)
+
+// DoubleClickTime is the maximum amount of time allowed between two
+// consecutive button presses for them to count as part of the same
+// multi-click. It is used by ButtonMultiPressFun.
+var DoubleClickTime = 400 * time.Millisecond
+
+// DoubleClickRadius is the maximum distance (in pixels, along either axis)
+// that the pointer may travel from the first press of a multi-click.
+// If the pointer moves any further, the click count is reset.
+// It is used by ButtonMultiPressFun.
+var DoubleClickRadius = 4
+
+// ButtonMultiPressFun represents a function that is called when a particular
+// mouse binding has been pressed some number of times in quick succession.
+// (i.e., a double-click or a triple-click.)
+type ButtonMultiPressFun xevent.ButtonPressFun
+
+// Connect attaches the multi-click callback to the button sequence on the
+// window provided. The callback is only run once 'clicks' presses of the same
+// button sequence have been seen, where each press happens within
+// DoubleClickTime of the last one and within DoubleClickRadius of the first
+// one. After the callback runs, the click count starts over.
+// 'sync' and 'grab' have the same meaning as they do in ButtonPressFun.
+// If 'sync' is true, presses that don't complete the multi-click are
+// replayed with Replay, so that the pointer isn't left frozen. The press
+// that completes it is left for the callback, which must call AllowEvents.
+//
+// Note that ButtonMultiPressFun cannot satisfy the xgbutil.CallbackMouse
+// interface, since it needs to know the number of clicks to respond to.
+func (callback ButtonMultiPressFun) Connect(xu *xgbutil.XUtil,
+	win xproto.Window, buttonStr string, clicks int, sync, grab bool) error {
+
+	mc := &multiClick{clicks: clicks}
+	return ButtonPressFun(
+		func(xu *xgbutil.XUtil, ev xevent.ButtonPressEvent) {
+			if mc.press(ev) {
+				callback(xu, ev)
+			} else if sync {
+				Replay(xu)
+			}
+		}).Connect(xu, win, buttonStr, sync, grab)
+}
+
+// multiClick keeps track of the state of a single multi-click binding.
+type multiClick struct {
+	clicks int
+	count  int
+	last   xproto.Timestamp
+	x, y   int
+}
+
+// press records a button press and returns true when the number of presses
+// seen has reached the number of clicks required. Presses that are too far
+// apart in time or space reset the count.
+func (mc *multiClick) press(ev xevent.ButtonPressEvent) bool {
+	x, y := int(ev.RootX), int(ev.RootY)
+	elapsed := time.Duration(ev.Time-mc.last) * time.Millisecond
+	if mc.count == 0 || elapsed > DoubleClickTime ||
+		abs(x-mc.x) > DoubleClickRadius || abs(y-mc.y) > DoubleClickRadius {
+
+		mc.count = 0
+		mc.x, mc.y = x, y
+	}
+	mc.last = ev.Time
+	mc.count++
+
+	if mc.count >= mc.clicks {
+		mc.count = 0
+		return true
+	}
+	return false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -41,7 +41,11 @@ way to determine the numbers of each button on your mouse is to launch the xev
 program in a terminal, click the corresponding button in the new window that
 opens, and read the event output in the terminal that launched xev. Usually a
 left click is button 1, a right click is button 3 and a middle click is button
-2.
+2. Scrolling up and down are typically buttons 4 and 5, while scrolling left
+and right (i.e., with a tilt wheel or a touchpad) are typically buttons 6 and
+7. Note that buttons 6 and 7 can be bound by number (like 'Mod4-6'), but
+cannot be used as modifiers, since X only tracks the state of buttons 1
+through 5.
 
 An example button sequence might look like 'Mod4-Control-Shift-1'. The
 mouse binding for that button sequence is activated when all three
@@ -103,6 +107,15 @@ press sent to the client window as well.
 Note that with a synchronous grab, all event processing will be halted by the X
 server until *some* call to xproto.AllowEvents is made.
 
+The mousebind.Replay function issues the replay request above for you. Even
+simpler, ButtonPressFun's ConnectReplay method establishes a synchronous grab
+and calls mousebind.Replay automatically after the callback returns:
+
+	mousebind.ButtonPressFun(
+		func(X *xgbutil.XUtil, ev xevent.ButtonPressEvent) {
+			// do something when button is pressed
+		}).ConnectReplay(XUtilValue, some-window-id, "1")
+
 Mouse bindings on the root window example
 
 To run a particular function whenever the 'Mod4-Control-Shift-1' button
@@ -147,6 +160,32 @@ can only discriminate at the event level.
 
 This is the kind of handler you might use to capture all button press events.
 
+Double-click example
+
+The ButtonMultiPressFun type works just like ButtonPressFun, except its
+Connect method also takes the number of clicks to respond to. The callback is
+only run when the button sequence is pressed that many times within
+mousebind.DoubleClickTime, and without the pointer moving further than
+mousebind.DoubleClickRadius pixels.
+
+	mousebind.ButtonMultiPressFun(
+		func(X *xgbutil.XUtil, ev xevent.ButtonPressEvent) {
+			// do something when button 1 is double-clicked
+		}).Connect(XUtilValue, your-window-id, "1", 2, false, false)
+
+Scroll example
+
+The ScrollFun type binds both buttons of a scroll wheel at once, and
+accumulates wheel events so that the callback is only run once for every
+'step' events in the same direction. The callback receives the number of
+steps scrolled, which is negative when scrolling up.
+
+	mousebind.ScrollFun(
+		func(X *xgbutil.XUtil, ev xevent.ButtonPressEvent, delta int) {
+			// scroll the document by 'delta' lines
+		}).Connect(XUtilValue, your-window-id, "", mousebind.ScrollVertical,
+		3, false)
+
 More examples
 
 A complete working example using the mousebind package can be found in
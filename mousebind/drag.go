@@ -33,6 +33,35 @@ func Drag(xu *xgbutil.XUtil, grabwin xproto.Window, win xproto.Window,
 	}
 }
 
+// DragDeltaFun is the kind of function used on each step of a drag started
+// with DragDelta. Instead of event coordinates, it is passed the distance
+// the pointer has travelled since the drag began.
+type DragDeltaFun func(xu *xgbutil.XUtil, rootX, rootY, dx, dy int)
+
+// DragDelta is just like Drag, except that the step function is given the
+// change in position relative to the root coordinates passed to 'begin'.
+// This is usually what a window manager wants when moving or resizing a
+// window, since the new geometry can be computed from the geometry of the
+// window at the start of the drag plus the delta.
+// 'begin' and 'end' behave precisely as they do in Drag.
+func DragDelta(xu *xgbutil.XUtil, grabwin xproto.Window, win xproto.Window,
+	buttonStr string, grab bool,
+	begin xgbutil.MouseDragBeginFun, step DragDeltaFun,
+	end xgbutil.MouseDragFun) {
+
+	var startX, startY int
+	deltaBegin := func(xu *xgbutil.XUtil, rootX, rootY,
+		eventX, eventY int) (bool, xproto.Cursor) {
+
+		startX, startY = rootX, rootY
+		return begin(xu, rootX, rootY, eventX, eventY)
+	}
+	deltaStep := func(xu *xgbutil.XUtil, rootX, rootY, eventX, eventY int) {
+		step(xu, rootX, rootY, rootX-startX, rootY-startY)
+	}
+	Drag(xu, grabwin, win, buttonStr, grab, deltaBegin, deltaStep, end)
+}
+
 // dragGrab is a shortcut for grabbing the pointer for a drag.
 func dragGrab(xu *xgbutil.XUtil, grabwin xproto.Window, win xproto.Window,
 	cursor xproto.Cursor) bool {
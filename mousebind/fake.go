@@ -0,0 +1,58 @@
+package mousebind
+
+import (
+	"fmt"
+
+	"github.com/jezek/xgb/xproto"
+	"github.com/jezek/xgb/xtest"
+
+	"github.com/jezek/xgbutil"
+)
+
+// FakeButton sends a synthetic press (or release, if 'press' is false) of
+// 'button' to the X server with the XTEST extension, as if the button was
+// pressed on the pointer. The event goes wherever a real button press would.
+// An error is returned if the XTEST extension isn't available.
+func FakeButton(xu *xgbutil.XUtil, button xproto.Button, press bool) error {
+	evtype := byte(xproto.ButtonRelease)
+	if press {
+		evtype = xproto.ButtonPress
+	}
+	return fakeInput(xu, "FakeButton", evtype, byte(button),
+		xproto.WindowNone, 0, 0)
+}
+
+// FakeMotion moves the pointer to (x, y) in root window coordinates with the
+// XTEST extension, as if the pointer was moved there by the user.
+// An error is returned if the XTEST extension isn't available.
+func FakeMotion(xu *xgbutil.XUtil, x, y int) error {
+	return fakeInput(xu, "FakeMotion", xproto.MotionNotify, 0,
+		xu.RootWin(), x, y)
+}
+
+// FakeMotionRelative is just like FakeMotion, except the pointer is moved by
+// (dx, dy) from where it currently is.
+func FakeMotionRelative(xu *xgbutil.XUtil, dx, dy int) error {
+	return fakeInput(xu, "FakeMotionRelative", xproto.MotionNotify, 1,
+		xproto.WindowNone, dx, dy)
+}
+
+// fakeInput initializes the XTEST extension if necessary, and sends a
+// synthetic event with it. 'caller' is used in error messages.
+func fakeInput(xu *xgbutil.XUtil, caller string, evtype, detail byte,
+	root xproto.Window, x, y int) error {
+
+	if !xu.ExtInitialized("XTEST") {
+		if err := xtest.Init(xu.Conn()); err != nil {
+			return fmt.Errorf("%s: The XTEST extension is not available: %s",
+				caller, err)
+		}
+	}
+
+	err := xtest.FakeInputChecked(xu.Conn(), evtype, detail, 0, root,
+		int16(x), int16(y), 0).Check()
+	if err != nil {
+		return fmt.Errorf("%s: Could not send event: %s", caller, err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package mousebind
+
+import (
+	"time"
+
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xevent"
+)
+
+// ButtonHoldFun represents a function that is called when a particular mouse
+// binding has been held down for some amount of time. (i.e., "press and
+// hold".)
+type ButtonHoldFun xevent.ButtonPressFun
+
+// Connect attaches the hold callback to the button sequence on the window
+// provided. The callback is run with the original ButtonPress event once the
+// button has been held down continuously for 'hold'. If the button is
+// released any earlier, the callback is not run.
+// 'grab' has the same meaning as it does in ButtonPressFun. The grab is
+// always asynchronous.
+//
+// The timer does not block the main event loop. When it expires, a client
+// message is sent to the dummy window, so that the callback is still run from
+// inside the main event loop like every other callback. (See xevent.Timer.)
+//
+// Button releases are watched on 'win', so 'win' must receive ButtonRelease
+// events. (This is always true when 'grab' is true. Otherwise, ButtonRelease
+// must be in the event mask of 'win'.)
+// The handlers attached to watch button releases are removed along with the
+// binding by Detach, DetachPress or DetachPressString.
+func (callback ButtonHoldFun) Connect(xu *xgbutil.XUtil, win xproto.Window,
+	buttonStr string, hold time.Duration, grab bool) error {
+
+	_, button, err := ParseString(xu, buttonStr)
+	if err != nil {
+		return err
+	}
+
+	bh := &buttonHold{button: button}
+	bh.timer, err = xevent.NewTimer(xu, func(xu *xgbutil.XUtil) {
+		callback(xu, bh.press)
+	})
+	if err != nil {
+		return err
+	}
+	release := xevent.ConnectHandle(xu, xevent.ButtonRelease, win,
+		xevent.ButtonReleaseFun(
+			func(xu *xgbutil.XUtil, ev xevent.ButtonReleaseEvent) {
+				if ev.Detail == bh.button {
+					bh.timer.Stop()
+				}
+			}))
+
+	owned := &ownedButtonPress{
+		ButtonPressFun: func(xu *xgbutil.XUtil, ev xevent.ButtonPressEvent) {
+			bh.press = ev
+			bh.timer.Reset(xu, hold)
+		},
+		detach: func(xu *xgbutil.XUtil) {
+			bh.timer.Detach(xu)
+			xevent.DetachHandle(xu, release)
+		},
+	}
+	err = connect(xu, owned, xevent.ButtonPress, win, buttonStr,
+		false, grab, nil)
+	if err != nil {
+		owned.detach(xu)
+		return err
+	}
+	return nil
+}
+
+// buttonHold keeps track of the state of a single hold binding.
+// It is only modified from inside the main event loop.
+type buttonHold struct {
+	button xproto.Button
+	press  xevent.ButtonPressEvent
+	timer  *xevent.Timer
+}
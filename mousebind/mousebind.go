@@ -20,6 +20,17 @@ var modifiers []uint16 = []uint16{ // order matters!
 	xproto.ButtonMaskAny,
 }
 
+// modifierNames are the names of each modifier in 'modifiers', as accepted
+// by ParseString.
+var modifierNames []string = []string{
+	"shift", "lock", "control",
+	"mod1", "mod2", "mod3",
+	"mod4", "mod5",
+	"button1", "button2", "button3",
+	"button4", "button5",
+	"any",
+}
+
 var pointerMasks uint16 = xproto.EventMaskPointerMotion |
 	xproto.EventMaskButtonRelease |
 	xproto.EventMaskButtonPress
@@ -35,7 +46,10 @@ func Initialize(xu *xgbutil.XUtil) {
 // i.e., 'Mod4-1', and returns a modifiers/button combination.
 // "Mod" could also be one of {button1, button2, button3, button4, button5}.
 // An error is returned if the string is malformed or if no BUTTONNUMBER
-// could be found.
+// could be found. Namely, any part of the string that is not a modifier name
+// must be a button number.
+// Since no grabs are made, ParseString can be used to validate button
+// sequences (i.e., from a configuration file) before connecting them.
 func ParseString(xu *xgbutil.XUtil, str string) (uint16, xproto.Button, error) {
 	mods, button := uint16(0), xproto.Button(0)
 	for _, part := range strings.Split(str, "-") {
@@ -66,17 +80,24 @@ func ParseString(xu *xgbutil.XUtil, str string) (uint16, xproto.Button, error) {
 			mods |= xproto.ButtonMask4
 		case "button5":
 			mods |= xproto.ButtonMask5
+		case "button6", "button7":
+			// The core protocol only has state masks for buttons 1 through 5,
+			// so there is no way to use these as modifiers. They can still
+			// be bound as buttons by number.
+			return 0, 0, fmt.Errorf("'%s' in the string '%s' cannot be used "+
+				"as a modifier since X has no state mask for it. To bind "+
+				"the button itself, use '%s' instead.", part, str, part[6:])
 		case "any":
 			mods |= xproto.ButtonMaskAny
 		default: // a button!
+			possible, err := strconv.ParseUint(part, 10, 8)
+			if err != nil {
+				return 0, 0, fmt.Errorf("'%s' in the string '%s' is neither "+
+					"a known modifier nor a valid 8-bit button number.",
+					part, str)
+			}
 			if button == 0 { // only accept the first button we see
-				possible, err := strconv.ParseUint(part, 10, 8)
-				if err == nil {
-					button = xproto.Button(possible)
-				} else {
-					return 0, 0, fmt.Errorf("Could not convert '%s' to a "+
-						"valid 8-bit integer.", part)
-				}
+				button = xproto.Button(possible)
 			}
 		}
 	}
@@ -89,6 +110,19 @@ func ParseString(xu *xgbutil.XUtil, str string) (uint16, xproto.Button, error) {
 	return mods, button, nil
 }
 
+// String is the inverse of ParseString. It takes a modifiers/button
+// combination and returns a button sequence like 'mod4-shift-1'.
+func String(mods uint16, button xproto.Button) string {
+	parts := make([]string, 0, 3)
+	for i, mod := range modifiers {
+		if mods&mod > 0 {
+			parts = append(parts, modifierNames[i])
+		}
+	}
+	parts = append(parts, strconv.Itoa(int(button)))
+	return strings.Join(parts, "-")
+}
+
 // Grab grabs a button with mods on a particular window.
 // Will also grab all combinations of modifiers found in xevent.IgnoreMods
 // If 'sync' is True, then no further events can be processed until the
@@ -120,13 +154,21 @@ func Grab(xu *xgbutil.XUtil, win xproto.Window, mods uint16,
 func GrabChecked(xu *xgbutil.XUtil, win xproto.Window, mods uint16,
 	button xproto.Button, sync bool) error {
 
+	return grabChecked(xu, win, mods, button, sync, xevent.IgnoreMods)
+}
+
+// grabChecked is the same as GrabChecked, except the modifiers to ignore
+// are given explicitly.
+func grabChecked(xu *xgbutil.XUtil, win xproto.Window, mods uint16,
+	button xproto.Button, sync bool, ignoreMods []uint16) error {
+
 	var pSync byte = xproto.GrabModeAsync
 	if sync {
 		pSync = xproto.GrabModeSync
 	}
 
 	var err error
-	for _, m := range xevent.IgnoreMods {
+	for _, m := range ignoreMods {
 		err = xproto.GrabButtonChecked(xu.Conn(), true, win, pointerMasks,
 			pSync, xproto.GrabModeAsync, 0, 0, byte(button), mods|m).Check()
 		if err != nil {
@@ -141,7 +183,15 @@ func GrabChecked(xu *xgbutil.XUtil, win xproto.Window, mods uint16,
 func Ungrab(xu *xgbutil.XUtil, win xproto.Window, mods uint16,
 	button xproto.Button) {
 
-	for _, m := range xevent.IgnoreMods {
+	ungrab(xu, win, mods, button, xevent.IgnoreMods)
+}
+
+// ungrab is the same as Ungrab, except the modifiers to ignore are given
+// explicitly.
+func ungrab(xu *xgbutil.XUtil, win xproto.Window, mods uint16,
+	button xproto.Button, ignoreMods []uint16) {
+
+	for _, m := range ignoreMods {
 		xproto.UngrabButtonChecked(xu.Conn(), byte(button), win, mods|m).Check()
 	}
 }
@@ -151,6 +201,10 @@ func Ungrab(xu *xgbutil.XUtil, win xproto.Window, mods uint16,
 // XGB. It is possible to not get an error and the grab to be unsuccessful.
 // The purpose of 'win' is that after a grab is successful, ALL Button*Events
 // will be sent to that window. Make sure you have a callback attached :-)
+// If 'confine' is not xproto.WindowNone, the pointer cannot leave that window
+// for the duration of the grab. (i.e., confine to the root window to keep
+// the pointer on screen during a resize.) If 'cursor' is not
+// xproto.CursorNone, it is displayed for the duration of the grab.
 func GrabPointer(xu *xgbutil.XUtil, win xproto.Window, confine xproto.Window,
 	cursor xproto.Cursor) (bool, error) {
 
@@ -165,6 +219,16 @@ func GrabPointer(xu *xgbutil.XUtil, win xproto.Window, confine xproto.Window,
 	return reply.Status == xproto.GrabStatusSuccess, nil
 }
 
+// Replay releases a synchronous passive grab and replays the button event
+// that activated it to the windows underneath the grabbing window.
+// It uses the time of the last event received, which should be the event
+// that activated the grab.
+// When a synchronous grab is activated, all pointer event processing on the
+// X server is stopped until some call to AllowEvents (like this one) is made.
+func Replay(xu *xgbutil.XUtil) {
+	xproto.AllowEvents(xu.Conn(), xproto.AllowReplayPointer, xu.TimeGet())
+}
+
 // UngrabPointer undoes GrabPointer.
 func UngrabPointer(xu *xgbutil.XUtil) {
 	xproto.UngrabPointer(xu.Conn(), 0)
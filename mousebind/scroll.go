@@ -0,0 +1,100 @@
+package mousebind
+
+import (
+	"time"
+
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xevent"
+)
+
+// ScrollResetTime is the maximum amount of time allowed between two wheel
+// events for them to be accumulated by ScrollFun. After a longer pause,
+// any partial step that has been accumulated is forgotten.
+var ScrollResetTime = 250 * time.Millisecond
+
+// ScrollAxis selects which scroll wheel buttons ScrollFun responds to.
+type ScrollAxis int
+
+const (
+	// ScrollVertical is buttons 4 (up) and 5 (down).
+	ScrollVertical ScrollAxis = iota
+
+	// ScrollHorizontal is buttons 6 (left) and 7 (right).
+	ScrollHorizontal
+)
+
+// ScrollFun represents a function that is called when a scroll wheel has
+// been turned far enough. 'delta' is the number of steps scrolled, which is
+// negative when scrolling up or left and positive when scrolling down or
+// right. 'ev' is the most recent wheel event.
+type ScrollFun func(xu *xgbutil.XUtil, ev xevent.ButtonPressEvent, delta int)
+
+// Connect attaches the scroll callback to both wheel buttons of 'axis' on the
+// window provided. 'mods' are the modifiers that must be held while
+// scrolling, in the same format used by ParseString (i.e., "Control" or
+// "Mod4-Shift"), or an empty string for none.
+// Wheel events are accumulated, and the callback is run once for every
+// 'step' events in the same direction, each within ScrollResetTime of the
+// last. Changing direction starts over. This keeps high-resolution wheels,
+// which send many events per notch, from scrolling too far.
+// 'grab' has the same meaning as it does in ButtonPressFun. The grab is
+// always asynchronous.
+//
+// Note that ScrollFun cannot satisfy the xgbutil.CallbackMouse interface,
+// since it needs to know the axis and step to respond to.
+func (callback ScrollFun) Connect(xu *xgbutil.XUtil, win xproto.Window,
+	mods string, axis ScrollAxis, step int, grab bool) error {
+
+	back, forward := "4", "5"
+	if axis == ScrollHorizontal {
+		back, forward = "6", "7"
+	}
+	if len(mods) > 0 {
+		back, forward = mods+"-"+back, mods+"-"+forward
+	}
+
+	sa := &scrollAccum{step: step}
+	if sa.step < 1 {
+		sa.step = 1
+	}
+	for _, binding := range []struct {
+		buttonStr string
+		dir       int
+	}{{back, -1}, {forward, 1}} {
+		dir := binding.dir
+		err := ButtonPressFun(
+			func(xu *xgbutil.XUtil, ev xevent.ButtonPressEvent) {
+				if delta := sa.scroll(ev, dir); delta != 0 {
+					callback(xu, ev, delta)
+				}
+			}).Connect(xu, win, binding.buttonStr, false, grab)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scrollAccum keeps track of the state of a single scroll binding.
+type scrollAccum struct {
+	step  int
+	count int
+	last  xproto.Timestamp
+}
+
+// scroll records a wheel event in the direction 'dir' (-1 or 1) and returns
+// the number of whole steps scrolled, keeping any remainder for later.
+func (sa *scrollAccum) scroll(ev xevent.ButtonPressEvent, dir int) int {
+	elapsed := time.Duration(ev.Time-sa.last) * time.Millisecond
+	if elapsed > ScrollResetTime || sa.count*dir < 0 {
+		sa.count = 0
+	}
+	sa.last = ev.Time
+	sa.count += dir
+
+	delta := sa.count / sa.step
+	sa.count -= delta * sa.step
+	return delta
+}
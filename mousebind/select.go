@@ -0,0 +1,130 @@
+package mousebind
+
+import (
+	"fmt"
+
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xevent"
+)
+
+// crosshairGlyph is the index of the crosshair cursor in the X cursor font.
+// (This is xcursor.Crosshair, which can't be imported here.)
+const crosshairGlyph = 34
+
+// SelectWindow lets the user pick a window by clicking on it, like xprop and
+// xwininfo do. The pointer is grabbed and shown as a crosshair until any
+// button is pressed, and the top-level window under the pointer is returned.
+// (With a reparenting window manager, this is the frame of the client.) If
+// the click is on the root window, the root window is returned.
+//
+// SelectWindow blocks until the user clicks, and the click is received by the
+// main event loop. It must therefore be called while the main event loop is
+// running in another goroutine, and never from inside an event handler.
+func SelectWindow(xu *xgbutil.XUtil) (xproto.Window, error) {
+	// The grab is put on a window of our own, so that the handler can be
+	// removed afterwards without disturbing any other handlers.
+	grabWin, err := xproto.NewWindowId(xu.Conn())
+	if err != nil {
+		return 0, fmt.Errorf("SelectWindow: %s", err)
+	}
+	err = xproto.CreateWindowChecked(xu.Conn(), 0, grabWin, xu.RootWin(),
+		-1, -1, 1, 1, 0, xproto.WindowClassInputOnly, 0,
+		xproto.CwOverrideRedirect, []uint32{1}).Check()
+	if err != nil {
+		return 0, fmt.Errorf("SelectWindow: Could not create grab window: %s",
+			err)
+	}
+	defer xproto.DestroyWindow(xu.Conn(), grabWin)
+
+	err = xproto.MapWindowChecked(xu.Conn(), grabWin).Check()
+	if err != nil {
+		return 0, fmt.Errorf("SelectWindow: Could not map grab window: %s",
+			err)
+	}
+
+	cursor, err := crosshairCursor(xu)
+	if err != nil {
+		return 0, fmt.Errorf("SelectWindow: Could not create cursor: %s", err)
+	}
+	defer xproto.FreeCursor(xu.Conn(), cursor)
+
+	clicked := make(chan xevent.ButtonPressEvent, 1)
+	xevent.ButtonPressFun(
+		func(xu *xgbutil.XUtil, ev xevent.ButtonPressEvent) {
+			select {
+			case clicked <- ev:
+			default:
+			}
+		}).Connect(xu, grabWin)
+	defer xevent.Detach(xu, grabWin)
+
+	status, err := GrabPointer(xu, grabWin, 0, cursor)
+	if err != nil {
+		return 0, fmt.Errorf("SelectWindow: %s", err)
+	}
+	if !status {
+		return 0, fmt.Errorf("SelectWindow: Could not grab the pointer.")
+	}
+	ev := <-clicked
+	UngrabPointer(xu)
+
+	win, err := WindowUnderPointer(xu, ev)
+	if err != nil {
+		return 0, fmt.Errorf("SelectWindow: %s", err)
+	}
+	return win, nil
+}
+
+// WindowUnderPointer returns the top-level window (i.e., the child of the
+// root window) that the button press 'ev' happened in. This is useful when
+// the event is reported to a grab window instead of the window that was
+// clicked. If the press wasn't in any top-level window, the root window is
+// returned.
+func WindowUnderPointer(xu *xgbutil.XUtil,
+	ev xevent.ButtonPressEvent) (xproto.Window, error) {
+
+	// The X server already found the window when the event is reported
+	// relative to the root window.
+	if ev.Event == ev.Root && ev.Child != 0 {
+		return ev.Child, nil
+	}
+
+	reply, err := xproto.TranslateCoordinates(xu.Conn(), ev.Root, ev.Root,
+		ev.RootX, ev.RootY).Reply()
+	if err != nil {
+		return 0, fmt.Errorf("WindowUnderPointer: Could not find the window "+
+			"at (%d, %d): %s", ev.RootX, ev.RootY, err)
+	}
+	if reply.Child == 0 {
+		return ev.Root, nil
+	}
+	return reply.Child, nil
+}
+
+// crosshairCursor creates a crosshair cursor from the X cursor font.
+func crosshairCursor(xu *xgbutil.XUtil) (xproto.Cursor, error) {
+	fontId, err := xproto.NewFontId(xu.Conn())
+	if err != nil {
+		return 0, err
+	}
+	err = xproto.OpenFontChecked(xu.Conn(), fontId,
+		uint16(len("cursor")), "cursor").Check()
+	if err != nil {
+		return 0, err
+	}
+	defer xproto.CloseFont(xu.Conn(), fontId)
+
+	cursorId, err := xproto.NewCursorId(xu.Conn())
+	if err != nil {
+		return 0, err
+	}
+	err = xproto.CreateGlyphCursorChecked(xu.Conn(), cursorId, fontId, fontId,
+		crosshairGlyph, crosshairGlyph+1,
+		0, 0, 0, 0xffff, 0xffff, 0xffff).Check()
+	if err != nil {
+		return 0, err
+	}
+	return cursorId, nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/jezek/xgb/xproto"
 
 	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xevent"
 )
 
 // attachMouseBindCallback associates an (event, window, mods, button)
@@ -62,14 +63,78 @@ func mouseCallbacks(xu *xgbutil.XUtil,
 }
 
 // runMouseBindCallbacks executes every callback corresponding to a
-// particular event/window/mod/button tuple.
+// particular event/window/state/button tuple.
+// The modifiers are deduced from the state using xevent.IgnoreMods, unless
+// a binding was connected with its own modifiers to ignore.
 func runMouseBindCallbacks(xu *xgbutil.XUtil, event interface{}, evtype int,
-	win xproto.Window, mods uint16, button xproto.Button) {
+	win xproto.Window, state uint16, detail xproto.Button) {
 
+	masked := mouseIgnoreModsAll(xu)
+
+	mods, button := DeduceButtonInfo(state, detail)
 	key := xgbutil.MouseKey{evtype, win, mods, button}
-	for _, cb := range mouseCallbacks(xu, key) {
-		cb.Run(xu, event)
+	if _, ok := masked[key]; !ok {
+		for _, cb := range mouseCallbacks(xu, key) {
+			cb.Run(xu, event)
+		}
+	}
+
+	// Bindings with their own modifiers to ignore can't be looked up
+	// directly, so check each of them.
+	for key, ignoreMods := range masked {
+		if key.Evtype != evtype || key.Win != win || key.Button != detail {
+			continue
+		}
+		mods, _ := deduceButtonInfo(state, detail, ignoreMods)
+		if mods != key.Mod {
+			continue
+		}
+		for _, cb := range mouseCallbacks(xu, key) {
+			cb.Run(xu, event)
+		}
+	}
+}
+
+// mouseIgnoreMods returns the modifiers to ignore for a particular key.
+// This is xevent.IgnoreMods unless the key was connected with its own masks.
+func mouseIgnoreMods(xu *xgbutil.XUtil, key xgbutil.MouseKey) []uint16 {
+	xu.MousebindsLck.RLock()
+	defer xu.MousebindsLck.RUnlock()
+
+	if ignoreMods, ok := xu.MouseIgnoreMods[key]; ok {
+		return ignoreMods
+	}
+	return xevent.IgnoreMods
+}
+
+// mouseIgnoreModsAll returns a copy of the 'MouseIgnoreMods' map.
+func mouseIgnoreModsAll(xu *xgbutil.XUtil) map[xgbutil.MouseKey][]uint16 {
+	xu.MousebindsLck.RLock()
+	defer xu.MousebindsLck.RUnlock()
+
+	masked := make(map[xgbutil.MouseKey][]uint16, len(xu.MouseIgnoreMods))
+	for key, ignoreMods := range xu.MouseIgnoreMods {
+		masked[key] = ignoreMods
 	}
+	return masked
+}
+
+// mouseIgnoreModsSet sets the modifiers to ignore for a particular key.
+func mouseIgnoreModsSet(xu *xgbutil.XUtil, key xgbutil.MouseKey,
+	ignoreMods []uint16) {
+
+	xu.MousebindsLck.Lock()
+	defer xu.MousebindsLck.Unlock()
+
+	xu.MouseIgnoreMods[key] = ignoreMods
+}
+
+// mouseIgnoreModsDel makes a particular key use xevent.IgnoreMods again.
+func mouseIgnoreModsDel(xu *xgbutil.XUtil, key xgbutil.MouseKey) {
+	xu.MousebindsLck.Lock()
+	defer xu.MousebindsLck.Unlock()
+
+	delete(xu.MouseIgnoreMods, key)
 }
 
 // connectedMouseBind checks to see if there are any key binds for a particular
@@ -92,19 +157,40 @@ func connectedMouseBind(xu *xgbutil.XUtil, evtype int, win xproto.Window) bool {
 // detachMouseBindWindow removes all callbacks associated with a particular
 // window and event type (either ButtonPress or ButtonRelease)
 // Also decrements the counter in the corresponding 'Mousegrabs' map
-// appropriately.
-func detachMouseBindWindow(xu *xgbutil.XUtil, evtype int, win xproto.Window) {
+// appropriately. The callbacks removed are returned.
+func detachMouseBindWindow(xu *xgbutil.XUtil, evtype int,
+	win xproto.Window) []xgbutil.CallbackMouse {
+
 	xu.MousebindsLck.Lock()
 	defer xu.MousebindsLck.Unlock()
 
 	// Since we can't create a full key, loop through all mouse binds
 	// and check if evtype and window match.
+	removed := make([]xgbutil.CallbackMouse, 0)
 	for key, _ := range xu.Mousebinds {
 		if key.Evtype == evtype && key.Win == win {
+			removed = append(removed, xu.Mousebinds[key]...)
 			xu.Mousegrabs[key] -= len(xu.Mousebinds[key])
 			delete(xu.Mousebinds, key)
 		}
 	}
+	return removed
+}
+
+// detachMouseBindKey removes all callbacks associated with a particular
+// event/window/mods/button combination.
+// Also decrements the counter in the corresponding 'Mousegrabs' map
+// appropriately. The callbacks removed are returned.
+func detachMouseBindKey(xu *xgbutil.XUtil,
+	key xgbutil.MouseKey) []xgbutil.CallbackMouse {
+
+	xu.MousebindsLck.Lock()
+	defer xu.MousebindsLck.Unlock()
+
+	removed := xu.Mousebinds[key]
+	xu.Mousegrabs[key] -= len(xu.Mousebinds[key])
+	delete(xu.Mousebinds, key)
+	return removed
 }
 
 // mouseBindGrabs returns the number of grabs on a particular
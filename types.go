@@ -8,6 +8,8 @@ mousebind.)
 */
 
 import (
+	"time"
+
 	"github.com/jezek/xgb"
 	"github.com/jezek/xgb/xproto"
 )
@@ -144,6 +146,25 @@ type ModifierMapping struct {
 //		}))
 type ErrorHandlerFun func(err xgb.Error)
 
+// EventFilterFun is the type of function used to filter events in the main
+// event loop before any hooks or callbacks are run. Returning false discards
+// the event. 'event' is the raw event value from XGB.
+// For example, to discard all key presses, use:
+//
+//	xevent.FilterSet(X, xgbutil.EventFilterFun(
+//		func(event interface{}) bool {
+//			_, ok := event.(xproto.KeyPressEvent)
+//			return !ok
+//		}))
+type EventFilterFun func(event interface{}) bool
+
+// AfterDispatchFun is the type of function run after the callbacks for an
+// event have been run in the main event loop. 'evtype' is the type of the
+// event (i.e., xevent.KeyPress), 'callbacks' is the number of callbacks that
+// were run and 'd' is how long it took to run them.
+// It can be set with xevent.AfterDispatchSet.
+type AfterDispatchFun func(evtype int, callbacks int, d time.Duration)
+
 // EventOrError is a struct that contains either an event value or an error
 // value. It is an error to contain both. Containing neither indicates an
 // error too.
@@ -4,6 +4,9 @@ predefined in the X 'cursor' font.
 
 All available cursors are predefined in cursordef.go.
 
+Cursors can also be created from any xgraphics.Image value with
+CreateCursorImage.
+
 Please see the 'change-cursor' example in the examples directory of the xgbutil
 package for an example of how to change the cursor when it enters a particular
 window.
@@ -0,0 +1,294 @@
+package xcursor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jezek/xgb/render"
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xgraphics"
+)
+
+// CreateCursorImage creates a cursor that looks like 'img', with its hot spot
+// at (hotX, hotY) relative to the top-left corner of the image.
+// If the RENDER extension (version 0.5 or newer) is available, the cursor
+// keeps the colors and alpha channel of the image. Otherwise, a two color
+// (black and white) approximation of the image is used, where pixels that are
+// less than half opaque are transparent.
+func CreateCursorImage(xu *xgbutil.XUtil, img *xgraphics.Image,
+	hotX, hotY int) (xproto.Cursor, error) {
+
+	if img.Rect.Empty() {
+		return 0, fmt.Errorf("CreateCursorImage: The image is empty.")
+	}
+	if err := renderVersion(xu, 0, 5); err == nil {
+		return createRenderCursor(xu, img, hotX, hotY)
+	}
+	return createBitmapCursor(xu, img, hotX, hotY)
+}
+
+// CreateAnimCursor creates an animated cursor that shows each image in
+// 'frames' for the corresponding duration in 'delays', and then starts over.
+// The animation is run by the X server. All frames share the hot spot at
+// (hotX, hotY).
+// This requires version 0.8 or newer of the RENDER extension. An error is
+// returned if it isn't available.
+func CreateAnimCursor(xu *xgbutil.XUtil, frames []*xgraphics.Image,
+	delays []time.Duration, hotX, hotY int) (xproto.Cursor, error) {
+
+	if len(frames) == 0 || len(frames) != len(delays) {
+		return 0, fmt.Errorf("CreateAnimCursor: There must be one delay "+
+			"for each frame, but there are %d frames and %d delays.",
+			len(frames), len(delays))
+	}
+	if err := renderVersion(xu, 0, 8); err != nil {
+		return 0, fmt.Errorf("CreateAnimCursor: %s", err)
+	}
+
+	elts := make([]render.Animcursorelt, len(frames))
+	for i, frame := range frames {
+		if frame.Rect.Empty() {
+			return 0, fmt.Errorf("CreateAnimCursor: Frame %d is empty.", i)
+		}
+		cursorId, err := createRenderCursor(xu, frame, hotX, hotY)
+		if err != nil {
+			return 0, err
+		}
+
+		// The animated cursor keeps its own reference to each frame.
+		defer xproto.FreeCursor(xu.Conn(), cursorId)
+		elts[i] = render.Animcursorelt{
+			Cursor: cursorId,
+			Delay:  uint32(delays[i] / time.Millisecond),
+		}
+	}
+
+	cursorId, err := xproto.NewCursorId(xu.Conn())
+	if err != nil {
+		return 0, err
+	}
+	err = render.CreateAnimCursorChecked(xu.Conn(), cursorId, elts).Check()
+	if err != nil {
+		return 0, err
+	}
+	return cursorId, nil
+}
+
+// renderVersion initializes the RENDER extension if necessary, and returns
+// an error if it isn't available or is older than the version given.
+func renderVersion(xu *xgbutil.XUtil, major, minor uint32) error {
+	if !xu.ExtInitialized("RENDER") {
+		if err := render.Init(xu.Conn()); err != nil {
+			return fmt.Errorf("The RENDER extension is not available: %s",
+				err)
+		}
+	}
+
+	reply, err := render.QueryVersion(xu.Conn(), 0, 11).Reply()
+	if err != nil {
+		return fmt.Errorf("Could not query the RENDER version: %s", err)
+	}
+	if reply.MajorVersion < major ||
+		(reply.MajorVersion == major && reply.MinorVersion < minor) {
+		return fmt.Errorf("RENDER version %d.%d is required, but the X "+
+			"server only supports version %d.%d.", major, minor,
+			reply.MajorVersion, reply.MinorVersion)
+	}
+	return nil
+}
+
+// argbFormat finds the RENDER picture format for 32 bit ARGB images, which
+// matches the pixel layout of xgraphics.Image.
+func argbFormat(xu *xgbutil.XUtil) (render.Pictformat, error) {
+	reply, err := render.QueryPictFormats(xu.Conn()).Reply()
+	if err != nil {
+		return 0, err
+	}
+	for _, format := range reply.Formats {
+		d := format.Direct
+		if format.Type == render.PictTypeDirect && format.Depth == 32 &&
+			d.AlphaShift == 24 && d.AlphaMask == 0xff &&
+			d.RedShift == 16 && d.RedMask == 0xff &&
+			d.GreenShift == 8 && d.GreenMask == 0xff &&
+			d.BlueShift == 0 && d.BlueMask == 0xff {
+
+			return format.Id, nil
+		}
+	}
+	return 0, fmt.Errorf("Could not find a 32 bit ARGB picture format.")
+}
+
+// createRenderCursor creates a full color cursor using the RENDER extension.
+func createRenderCursor(xu *xgbutil.XUtil, img *xgraphics.Image,
+	hotX, hotY int) (xproto.Cursor, error) {
+
+	format, err := argbFormat(xu)
+	if err != nil {
+		return 0, err
+	}
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+
+	pix, err := xproto.NewPixmapId(xu.Conn())
+	if err != nil {
+		return 0, err
+	}
+	err = xproto.CreatePixmapChecked(xu.Conn(), 32, pix,
+		xproto.Drawable(xu.RootWin()), uint16(width), uint16(height)).Check()
+	if err != nil {
+		return 0, err
+	}
+	defer xproto.FreePixmap(xu.Conn(), pix)
+
+	gc, err := xproto.NewGcontextId(xu.Conn())
+	if err != nil {
+		return 0, err
+	}
+	err = xproto.CreateGCChecked(xu.Conn(), gc, xproto.Drawable(pix),
+		0, nil).Check()
+	if err != nil {
+		return 0, err
+	}
+	defer xproto.FreeGC(xu.Conn(), gc)
+
+	// xgraphics.Image stores premultiplied BGRA, which is exactly what
+	// RENDER expects for ARGB cursors on a least significant byte first X
+	// server. Otherwise, the bytes of each pixel are in the reverse order.
+	msbFirst := xu.Setup().ImageByteOrder == xproto.ImageOrderMSBFirst
+	data := make([]byte, 0, 4*width*height)
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		i := img.PixOffset(img.Rect.Min.X, y)
+		row := img.Pix[i : i+4*width]
+		if !msbFirst {
+			data = append(data, row...)
+			continue
+		}
+		for x := 0; x < len(row); x += 4 {
+			data = append(data, row[x+3], row[x+2], row[x+1], row[x])
+		}
+	}
+	err = xproto.PutImageChecked(xu.Conn(), xproto.ImageFormatZPixmap,
+		xproto.Drawable(pix), gc, uint16(width), uint16(height), 0, 0,
+		0, 32, data).Check()
+	if err != nil {
+		return 0, err
+	}
+
+	pic, err := render.NewPictureId(xu.Conn())
+	if err != nil {
+		return 0, err
+	}
+	err = render.CreatePictureChecked(xu.Conn(), pic, xproto.Drawable(pix),
+		format, 0, nil).Check()
+	if err != nil {
+		return 0, err
+	}
+	defer render.FreePicture(xu.Conn(), pic)
+
+	cursorId, err := xproto.NewCursorId(xu.Conn())
+	if err != nil {
+		return 0, err
+	}
+	err = render.CreateCursorChecked(xu.Conn(), cursorId, pic,
+		uint16(hotX), uint16(hotY)).Check()
+	if err != nil {
+		return 0, err
+	}
+	return cursorId, nil
+}
+
+// createBitmapCursor creates a black and white cursor from the core protocol.
+func createBitmapCursor(xu *xgbutil.XUtil, img *xgraphics.Image,
+	hotX, hotY int) (xproto.Cursor, error) {
+
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	setup := xu.Setup()
+	pad := int(setup.BitmapFormatScanlinePad)
+	stride := (width + pad - 1) / pad * pad / 8
+	lsbFirst := setup.BitmapFormatBitOrder == xproto.ImageOrderLSBFirst
+
+	// Dark pixels are drawn with the foreground color (black) and light
+	// pixels with the background color (white).
+	source := make([]byte, stride*height)
+	mask := make([]byte, stride*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := img.At(img.Rect.Min.X+x, img.Rect.Min.Y+y).(xgraphics.BGRA)
+			if c.A < 0x80 {
+				continue
+			}
+			bit := byte(1 << uint(x%8))
+			if !lsbFirst {
+				bit = byte(0x80 >> uint(x%8))
+			}
+			i := y*stride + x/8
+			mask[i] |= bit
+			lum := (299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000
+			if lum*0xff < 0x80*int(c.A) {
+				source[i] |= bit
+			}
+		}
+	}
+
+	sourcePix, err := createBitmap(xu, width, height, source)
+	if err != nil {
+		return 0, err
+	}
+	defer xproto.FreePixmap(xu.Conn(), sourcePix)
+
+	maskPix, err := createBitmap(xu, width, height, mask)
+	if err != nil {
+		return 0, err
+	}
+	defer xproto.FreePixmap(xu.Conn(), maskPix)
+
+	cursorId, err := xproto.NewCursorId(xu.Conn())
+	if err != nil {
+		return 0, err
+	}
+	err = xproto.CreateCursorChecked(xu.Conn(), cursorId, sourcePix, maskPix,
+		0, 0, 0, 0xffff, 0xffff, 0xffff, uint16(hotX), uint16(hotY)).Check()
+	if err != nil {
+		return 0, err
+	}
+	return cursorId, nil
+}
+
+// createBitmap creates a pixmap of depth 1 from bitmap data in the format
+// specified by the X server's setup information.
+func createBitmap(xu *xgbutil.XUtil, width, height int,
+	data []byte) (xproto.Pixmap, error) {
+
+	pix, err := xproto.NewPixmapId(xu.Conn())
+	if err != nil {
+		return 0, err
+	}
+	err = xproto.CreatePixmapChecked(xu.Conn(), 1, pix,
+		xproto.Drawable(xu.RootWin()), uint16(width), uint16(height)).Check()
+	if err != nil {
+		return 0, err
+	}
+
+	gc, err := xproto.NewGcontextId(xu.Conn())
+	if err != nil {
+		xproto.FreePixmap(xu.Conn(), pix)
+		return 0, err
+	}
+	err = xproto.CreateGCChecked(xu.Conn(), gc, xproto.Drawable(pix),
+		xproto.GcForeground|xproto.GcBackground, []uint32{1, 0}).Check()
+	if err != nil {
+		xproto.FreePixmap(xu.Conn(), pix)
+		return 0, err
+	}
+	defer xproto.FreeGC(xu.Conn(), gc)
+
+	err = xproto.PutImageChecked(xu.Conn(), xproto.ImageFormatXYBitmap,
+		xproto.Drawable(pix), gc, uint16(width), uint16(height), 0, 0,
+		0, 1, data).Check()
+	if err != nil {
+		xproto.FreePixmap(xu.Conn(), pix)
+		return 0, err
+	}
+	return pix, nil
+}
@@ -21,6 +21,11 @@ func (callback KeyPressFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, KeyPress, win, callback)
 }
 
+func (callback KeyPressFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, KeyPress, win, priority, callback)
+}
+
 func (callback KeyPressFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(KeyPressEvent))
 }
@@ -32,6 +37,11 @@ func (callback KeyReleaseFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, KeyRelease, win, callback)
 }
 
+func (callback KeyReleaseFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, KeyRelease, win, priority, callback)
+}
+
 func (callback KeyReleaseFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(KeyReleaseEvent))
 }
@@ -43,6 +53,11 @@ func (callback ButtonPressFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, ButtonPress, win, callback)
 }
 
+func (callback ButtonPressFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, ButtonPress, win, priority, callback)
+}
+
 func (callback ButtonPressFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(ButtonPressEvent))
 }
@@ -54,6 +69,11 @@ func (callback ButtonReleaseFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, ButtonRelease, win, callback)
 }
 
+func (callback ButtonReleaseFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, ButtonRelease, win, priority, callback)
+}
+
 func (callback ButtonReleaseFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(ButtonReleaseEvent))
 }
@@ -65,6 +85,11 @@ func (callback MotionNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, MotionNotify, win, callback)
 }
 
+func (callback MotionNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, MotionNotify, win, priority, callback)
+}
+
 func (callback MotionNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(MotionNotifyEvent))
 }
@@ -76,6 +101,11 @@ func (callback EnterNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, EnterNotify, win, callback)
 }
 
+func (callback EnterNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, EnterNotify, win, priority, callback)
+}
+
 func (callback EnterNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(EnterNotifyEvent))
 }
@@ -87,6 +117,11 @@ func (callback LeaveNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, LeaveNotify, win, callback)
 }
 
+func (callback LeaveNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, LeaveNotify, win, priority, callback)
+}
+
 func (callback LeaveNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(LeaveNotifyEvent))
 }
@@ -98,6 +133,11 @@ func (callback FocusInFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, FocusIn, win, callback)
 }
 
+func (callback FocusInFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, FocusIn, win, priority, callback)
+}
+
 func (callback FocusInFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(FocusInEvent))
 }
@@ -109,6 +149,11 @@ func (callback FocusOutFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, FocusOut, win, callback)
 }
 
+func (callback FocusOutFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, FocusOut, win, priority, callback)
+}
+
 func (callback FocusOutFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(FocusOutEvent))
 }
@@ -120,6 +165,11 @@ func (callback KeymapNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, KeymapNotify, win, callback)
 }
 
+func (callback KeymapNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, KeymapNotify, win, priority, callback)
+}
+
 func (callback KeymapNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(KeymapNotifyEvent))
 }
@@ -131,6 +181,11 @@ func (callback ExposeFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, Expose, win, callback)
 }
 
+func (callback ExposeFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, Expose, win, priority, callback)
+}
+
 func (callback ExposeFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(ExposeEvent))
 }
@@ -142,6 +197,11 @@ func (callback GraphicsExposureFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, GraphicsExposure, win, callback)
 }
 
+func (callback GraphicsExposureFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, GraphicsExposure, win, priority, callback)
+}
+
 func (callback GraphicsExposureFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(GraphicsExposureEvent))
 }
@@ -153,6 +213,11 @@ func (callback NoExposureFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, NoExposure, win, callback)
 }
 
+func (callback NoExposureFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, NoExposure, win, priority, callback)
+}
+
 func (callback NoExposureFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(NoExposureEvent))
 }
@@ -164,6 +229,11 @@ func (callback VisibilityNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, VisibilityNotify, win, callback)
 }
 
+func (callback VisibilityNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, VisibilityNotify, win, priority, callback)
+}
+
 func (callback VisibilityNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(VisibilityNotifyEvent))
 }
@@ -175,6 +245,11 @@ func (callback CreateNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, CreateNotify, win, callback)
 }
 
+func (callback CreateNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, CreateNotify, win, priority, callback)
+}
+
 func (callback CreateNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(CreateNotifyEvent))
 }
@@ -186,6 +261,11 @@ func (callback DestroyNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, DestroyNotify, win, callback)
 }
 
+func (callback DestroyNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, DestroyNotify, win, priority, callback)
+}
+
 func (callback DestroyNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(DestroyNotifyEvent))
 }
@@ -197,6 +277,11 @@ func (callback UnmapNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, UnmapNotify, win, callback)
 }
 
+func (callback UnmapNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, UnmapNotify, win, priority, callback)
+}
+
 func (callback UnmapNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(UnmapNotifyEvent))
 }
@@ -208,6 +293,11 @@ func (callback MapNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, MapNotify, win, callback)
 }
 
+func (callback MapNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, MapNotify, win, priority, callback)
+}
+
 func (callback MapNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(MapNotifyEvent))
 }
@@ -219,6 +309,11 @@ func (callback MapRequestFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, MapRequest, win, callback)
 }
 
+func (callback MapRequestFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, MapRequest, win, priority, callback)
+}
+
 func (callback MapRequestFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(MapRequestEvent))
 }
@@ -230,6 +325,11 @@ func (callback ReparentNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, ReparentNotify, win, callback)
 }
 
+func (callback ReparentNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, ReparentNotify, win, priority, callback)
+}
+
 func (callback ReparentNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(ReparentNotifyEvent))
 }
@@ -241,6 +341,11 @@ func (callback ConfigureNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, ConfigureNotify, win, callback)
 }
 
+func (callback ConfigureNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, ConfigureNotify, win, priority, callback)
+}
+
 func (callback ConfigureNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(ConfigureNotifyEvent))
 }
@@ -252,6 +357,11 @@ func (callback ConfigureRequestFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, ConfigureRequest, win, callback)
 }
 
+func (callback ConfigureRequestFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, ConfigureRequest, win, priority, callback)
+}
+
 func (callback ConfigureRequestFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(ConfigureRequestEvent))
 }
@@ -263,6 +373,11 @@ func (callback GravityNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, GravityNotify, win, callback)
 }
 
+func (callback GravityNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, GravityNotify, win, priority, callback)
+}
+
 func (callback GravityNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(GravityNotifyEvent))
 }
@@ -274,6 +389,11 @@ func (callback ResizeRequestFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, ResizeRequest, win, callback)
 }
 
+func (callback ResizeRequestFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, ResizeRequest, win, priority, callback)
+}
+
 func (callback ResizeRequestFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(ResizeRequestEvent))
 }
@@ -285,6 +405,11 @@ func (callback CirculateNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, CirculateNotify, win, callback)
 }
 
+func (callback CirculateNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, CirculateNotify, win, priority, callback)
+}
+
 func (callback CirculateNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(CirculateNotifyEvent))
 }
@@ -296,6 +421,11 @@ func (callback CirculateRequestFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, CirculateRequest, win, callback)
 }
 
+func (callback CirculateRequestFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, CirculateRequest, win, priority, callback)
+}
+
 func (callback CirculateRequestFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(CirculateRequestEvent))
 }
@@ -307,6 +437,11 @@ func (callback PropertyNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, PropertyNotify, win, callback)
 }
 
+func (callback PropertyNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, PropertyNotify, win, priority, callback)
+}
+
 func (callback PropertyNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(PropertyNotifyEvent))
 }
@@ -318,6 +453,11 @@ func (callback SelectionClearFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, SelectionClear, win, callback)
 }
 
+func (callback SelectionClearFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, SelectionClear, win, priority, callback)
+}
+
 func (callback SelectionClearFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(SelectionClearEvent))
 }
@@ -329,6 +469,11 @@ func (callback SelectionRequestFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, SelectionRequest, win, callback)
 }
 
+func (callback SelectionRequestFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, SelectionRequest, win, priority, callback)
+}
+
 func (callback SelectionRequestFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(SelectionRequestEvent))
 }
@@ -340,6 +485,11 @@ func (callback SelectionNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, SelectionNotify, win, callback)
 }
 
+func (callback SelectionNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, SelectionNotify, win, priority, callback)
+}
+
 func (callback SelectionNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(SelectionNotifyEvent))
 }
@@ -351,6 +501,11 @@ func (callback ColormapNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, ColormapNotify, win, callback)
 }
 
+func (callback ColormapNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, ColormapNotify, win, priority, callback)
+}
+
 func (callback ColormapNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(ColormapNotifyEvent))
 }
@@ -362,6 +517,11 @@ func (callback ClientMessageFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, ClientMessage, win, callback)
 }
 
+func (callback ClientMessageFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, ClientMessage, win, priority, callback)
+}
+
 func (callback ClientMessageFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(ClientMessageEvent))
 }
@@ -373,6 +533,11 @@ func (callback MappingNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, MappingNotify, win, callback)
 }
 
+func (callback MappingNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, MappingNotify, win, priority, callback)
+}
+
 func (callback MappingNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(MappingNotifyEvent))
 }
@@ -384,6 +549,11 @@ func (callback ShapeNotifyFun) Connect(xu *xgbutil.XUtil,
 	attachCallback(xu, ShapeNotify, win, callback)
 }
 
+func (callback ShapeNotifyFun) ConnectPriority(xu *xgbutil.XUtil,
+	win xproto.Window, priority int) {
+	attachCallbackPriority(xu, ShapeNotify, win, priority, callback)
+}
+
 func (callback ShapeNotifyFun) Run(xu *xgbutil.XUtil, event interface{}) {
 	callback(xu, event.(ShapeNotifyEvent))
 }
@@ -68,6 +68,21 @@ your-window-id.
 			fmt.Printf("(%d, %d) %dx%d\n", e.X, e.Y, e.Width, e.Height)
 		}).Connect(XUtilValue, your-window-id)
 
+Callback order
+
+Callbacks attached to the same (event, window) tuple with Connect are run in
+the order in which they were attached. The ConnectPriority method attaches a
+callback with a priority: callbacks with a higher priority are run first, and
+callbacks attached with Connect have a priority of 0. A callback may call
+xevent.StopPropagation to prevent the remaining callbacks from seeing the
+current event.
+
+	xevent.KeyPressFun(
+		func(X *xgbutil.XUtil, e xevent.KeyPressEvent) {
+			// handle the key press before anyone else
+			xevent.StopPropagation(X)
+		}).ConnectPriority(XUtilValue, your-window-id, 10)
+
 More examples
 
 The xevent package is used in several of the examples in the examples directory
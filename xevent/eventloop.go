@@ -11,6 +11,12 @@ loop.
 */
 
 import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/randr"
 	"github.com/jezek/xgb/shape"
 	"github.com/jezek/xgb/xproto"
 
@@ -24,7 +30,12 @@ func Read(xu *xgbutil.XUtil, block bool) {
 	if block {
 		ev, err := xu.Conn().WaitForEvent()
 		if ev == nil && err == nil {
-			xgbutil.Logger.Fatal("BUG: Could not read an event or an error.")
+			// The connection to the X server has been closed, so the main
+			// event loop can't do anything else. (See XUtil.Reconnect.)
+			xgbutil.Logger.Println("The connection to the X server was " +
+				"closed. Stopping the main event loop.")
+			Quit(xu)
+			return
 		}
 		Enqueue(xu, ev, err)
 	}
@@ -49,7 +60,55 @@ func Read(xu *xgbutil.XUtil, block bool) {
 // able to run this in different goroutines concurrently. However, only
 // *one* of these should run for *each* connection.
 func Main(xu *xgbutil.XUtil) {
-	mainEventLoop(xu, nil, nil, nil)
+	mainEventLoop(context.Background(), xu, nil, nil, nil)
+}
+
+// MainContext is the same as Main, except it also stops when 'ctx' is
+// cancelled. Any callbacks running when 'ctx' is cancelled are allowed to
+// finish before MainContext returns, but no further events are processed.
+// Cancelling 'ctx' has the same effect as calling Quit.
+func MainContext(ctx context.Context, xu *xgbutil.XUtil) {
+	name := "_XGBUTIL_WAKE"
+	reply, err := xproto.InternAtom(xu.Conn(), false, uint16(len(name)),
+		name).Reply()
+	if err != nil {
+		xgbutil.Logger.Printf("MainContext: Could not intern atom '%s': %s",
+			name, err)
+	}
+
+	// Wait for the goroutine to exit before returning, so that it can't
+	// use the connection after MainContext has returned. (i.e., while
+	// XUtil.Reconnect is replacing it.)
+	done, exited := make(chan struct{}), make(chan struct{})
+	defer func() {
+		close(done)
+		<-exited
+	}()
+
+	// The main event loop checks 'ctx' itself. This only makes sure that it
+	// isn't left blocked waiting for an event.
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			if reply != nil {
+				wake(xu, reply.Atom)
+			}
+		case <-done:
+		}
+	}()
+	mainEventLoop(ctx, xu, nil, nil, nil)
+}
+
+// wake sends a client message to the dummy window, so that an event loop
+// blocked waiting for an event can notice that it should quit.
+func wake(xu *xgbutil.XUtil, atom xproto.Atom) {
+	cm, err := NewClientMessage(32, xu.Dummy(), atom)
+	if err != nil {
+		xgbutil.Logger.Printf("Could not wake the main event loop: %s", err)
+		return
+	}
+	xproto.SendEvent(xu.Conn(), false, xu.Dummy(), 0, string(cm.Bytes()))
 }
 
 // MainPing starts the main X event loop, and returns three "ping" channels:
@@ -84,15 +143,83 @@ func MainPing(xu *xgbutil.XUtil) (chan struct{}, chan struct{}, chan struct{}) {
 	pingAfter := make(chan struct{}, 0)
 	pingQuit := make(chan struct{}, 0)
 	go func() {
-		mainEventLoop(xu, pingBefore, pingAfter, pingQuit)
+		mainEventLoop(context.Background(), xu, pingBefore, pingAfter,
+			pingQuit)
 	}()
 	return pingBefore, pingAfter, pingQuit
 }
 
+// readPollInterval is how often ReadAndProcess checks for new events while
+// it waits.
+const readPollInterval = 5 * time.Millisecond
+
+// ReadAndProcess processes at most one event, waiting up to 'timeout' for
+// one to arrive if the queue is empty. It returns whether an event or error
+// was processed. If the item processed was an error, it is returned rather
+// than sent to the error handler.
+// This is useful for programs that need to interleave X event processing
+// with other work in a single goroutine, instead of running Main or MainPing.
+// (The connection is polled while waiting, so no goroutines are started.)
+// Polling can't tell an idle connection from a closed one, so when 'timeout'
+// expires without an event, a round trip is made to the X server. If the
+// connection has been closed, Quit is called and an error is returned.
+// (See XUtil.Reconnect.)
+func ReadAndProcess(xu *xgbutil.XUtil, timeout time.Duration) (bool, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		Read(xu, false)
+		if !Empty(xu) {
+			break
+		}
+
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			_, err := xproto.GetInputFocus(xu.Conn()).Reply()
+			if err != nil {
+				Quit(xu)
+				return false, fmt.Errorf("ReadAndProcess: The connection "+
+					"to the X server was closed: %s", err)
+			}
+			return false, nil
+		}
+		if remaining > readPollInterval {
+			remaining = readPollInterval
+		}
+		time.Sleep(remaining)
+	}
+
+	ev, err := Dequeue(xu)
+	if err != nil {
+		return true, err
+	}
+	processEvent(xu, compressEvent(xu, ev))
+	return true, nil
+}
+
+// Dispatch processes 'ev' right away, exactly like the main event loop
+// processes events read from the X server: the event filter and hooks are
+// run first, and then the callbacks connected to the event's window.
+// Nothing is sent to or read from the X server, which makes Dispatch useful
+// for testing callbacks with fabricated events. (i.e., an
+// xproto.KeyPressEvent value built by hand.)
+// Dispatch never touches the event queue, so 'ev' is not compressed with
+// queued events, even if motion compression is enabled.
+// Dispatch runs the callbacks in the calling goroutine, so it should not be
+// called while the main event loop is running in another goroutine. Use
+// Enqueue to add an event to the queue of a running main event loop instead.
+func Dispatch(xu *xgbutil.XUtil, ev xgb.Event) {
+	processEvent(xu, ev)
+}
+
 // mainEventLoop runs the main event loop with an optional ping channel.
-func mainEventLoop(xu *xgbutil.XUtil,
+// The loop quits when 'ctx' is cancelled. 'ctx' is checked from inside the
+// loop, so that Quit is never called from another goroutine.
+func mainEventLoop(ctx context.Context, xu *xgbutil.XUtil,
 	pingBefore, pingAfter, pingQuit chan struct{}) {
 	for {
+		if ctx.Err() != nil {
+			Quit(xu)
+		}
 		if Quitting(xu) {
 			if pingQuit != nil {
 				pingQuit <- struct{}{}
@@ -105,13 +232,18 @@ func mainEventLoop(xu *xgbutil.XUtil,
 		Read(xu, true)
 
 		// Now process every event/error in the queue.
-		processEventQueue(xu, pingBefore, pingAfter)
+		processEventQueue(ctx, xu, pingBefore, pingAfter)
 	}
 }
 
 // processEventQueue processes every item in the event/error queue.
-func processEventQueue(xu *xgbutil.XUtil, pingBefore, pingAfter chan struct{}) {
+func processEventQueue(ctx context.Context, xu *xgbutil.XUtil,
+	pingBefore, pingAfter chan struct{}) {
+
 	for !Empty(xu) {
+		if ctx.Err() != nil {
+			Quit(xu)
+		}
 		if Quitting(xu) {
 			return
 		}
@@ -128,164 +260,227 @@ func processEventQueue(xu *xgbutil.XUtil, pingBefore, pingAfter chan struct{}) {
 		// and move on the next event/error.
 		if err != nil {
 			ErrorHandlerGet(xu)(err)
-			if pingBefore != nil && pingAfter != nil {
-				pingAfter <- struct{}{}
-			}
-			continue
+		} else {
+			processEvent(xu, compressEvent(xu, ev))
 		}
 
-		// We know there isn't an error. If there isn't an event either,
-		// then there's a bug somewhere.
-		if ev == nil {
-			xgbutil.Logger.Fatal("BUG: Expected an event but got nil.")
+		if pingBefore != nil && pingAfter != nil {
+			pingAfter <- struct{}{}
 		}
+	}
+}
 
-		hooks := getHooks(xu)
-		for _, hook := range hooks {
-			if !hook.Run(xu, ev) {
-				goto END
-			}
+// processEvent runs the event filter, the hooks and the callbacks for a
+// single event.
+func processEvent(xu *xgbutil.XUtil, ev xgb.Event) {
+	// We know there isn't an error. If there isn't an event either,
+	// then there's a bug somewhere.
+	if ev == nil {
+		xgbutil.Logger.Fatal("BUG: Expected an event but got nil.")
+	}
+
+	if filter := FilterGet(xu); filter != nil && !filter(ev) {
+		return
+	}
+
+	start := time.Now()
+	for _, hook := range getHooks(xu) {
+		if !hook.Run(xu, ev) || Quitting(xu) {
+			return
 		}
+	}
 
-		switch event := ev.(type) {
-		case xproto.KeyPressEvent:
-			e := KeyPressEvent{&event}
+	evtype, n := dispatchEvent(xu, ev)
+	if after := AfterDispatchGet(xu); after != nil {
+		after(evtype, n, time.Since(start))
+	}
+}
 
-			// If we're redirecting key events, this is the place to do it!
-			if wid := RedirectKeyGet(xu); wid > 0 {
-				e.Event = wid
-			}
+// compressEvent returns the event to process in place of 'ev', which has
+// just been dequeued. This is 'ev' itself, unless it is compressed with
+// events that follow it in the queue. (See MotionCompressionSet.)
+// Compression happens before the event filter and hooks are run, so they
+// only see the events that are actually processed.
+func compressEvent(xu *xgbutil.XUtil, ev xgb.Event) xgb.Event {
+	if mn, ok := ev.(xproto.MotionNotifyEvent); ok && MotionCompressionGet(xu) {
+		return compressMotionNotify(xu, mn)
+	}
+	return ev
+}
 
-			xu.TimeSet(e.Time)
-			runCallbacks(xu, e, KeyPress, e.Event)
-		case xproto.KeyReleaseEvent:
-			e := KeyReleaseEvent{&event}
+// compressMotionNotify dequeues every MotionNotify event at the front of the
+// queue that is equivalent to 'ev' (other than the pointer position), and
+// returns the most recent one.
+func compressMotionNotify(xu *xgbutil.XUtil,
+	ev xproto.MotionNotifyEvent) xproto.MotionNotifyEvent {
 
-			// If we're redirecting key events, this is the place to do it!
-			if wid := RedirectKeyGet(xu); wid > 0 {
-				e.Event = wid
-			}
+	for _, ee := range Peek(xu) {
+		if ee.Err != nil {
+			break
+		}
+		mn, ok := ee.Event.(xproto.MotionNotifyEvent)
+		if !ok || ev.Event != mn.Event || ev.Child != mn.Child ||
+			ev.Detail != mn.Detail || ev.State != mn.State ||
+			ev.Root != mn.Root || ev.SameScreen != mn.SameScreen {
 
-			xu.TimeSet(e.Time)
-			runCallbacks(xu, e, KeyRelease, e.Event)
-		case xproto.ButtonPressEvent:
-			e := ButtonPressEvent{&event}
-			xu.TimeSet(e.Time)
-			runCallbacks(xu, e, ButtonPress, e.Event)
-		case xproto.ButtonReleaseEvent:
-			e := ButtonReleaseEvent{&event}
-			xu.TimeSet(e.Time)
-			runCallbacks(xu, e, ButtonRelease, e.Event)
-		case xproto.MotionNotifyEvent:
-			e := MotionNotifyEvent{&event}
-			xu.TimeSet(e.Time)
-			runCallbacks(xu, e, MotionNotify, e.Event)
-		case xproto.EnterNotifyEvent:
-			e := EnterNotifyEvent{&event}
-			xu.TimeSet(e.Time)
-			runCallbacks(xu, e, EnterNotify, e.Event)
-		case xproto.LeaveNotifyEvent:
-			e := LeaveNotifyEvent{&event}
-			xu.TimeSet(e.Time)
-			runCallbacks(xu, e, LeaveNotify, e.Event)
-		case xproto.FocusInEvent:
-			e := FocusInEvent{&event}
-			runCallbacks(xu, e, FocusIn, e.Event)
-		case xproto.FocusOutEvent:
-			e := FocusOutEvent{&event}
-			runCallbacks(xu, e, FocusOut, e.Event)
-		case xproto.KeymapNotifyEvent:
-			e := KeymapNotifyEvent{&event}
-			runCallbacks(xu, e, KeymapNotify, NoWindow)
-		case xproto.ExposeEvent:
-			e := ExposeEvent{&event}
-			runCallbacks(xu, e, Expose, e.Window)
-		case xproto.GraphicsExposureEvent:
-			e := GraphicsExposureEvent{&event}
+			break
+		}
+		Dequeue(xu)
+		ev = mn
+	}
+	return ev
+}
+
+// dispatchEvent runs the callbacks for a single event. It returns the type of
+// the event and the number of callbacks that were run.
+func dispatchEvent(xu *xgbutil.XUtil, ev xgb.Event) (int, int) {
+	switch event := ev.(type) {
+	case xproto.KeyPressEvent:
+		e := KeyPressEvent{&event}
+
+		// If we're redirecting key events, this is the place to do it!
+		if wid := RedirectKeyGet(xu); wid > 0 {
+			e.Event = wid
+		}
+
+		xu.TimeSet(e.Time)
+		return KeyPress, runCallbacks(xu, e, KeyPress, e.Event)
+	case xproto.KeyReleaseEvent:
+		e := KeyReleaseEvent{&event}
+
+		// If we're redirecting key events, this is the place to do it!
+		if wid := RedirectKeyGet(xu); wid > 0 {
+			e.Event = wid
+		}
+
+		xu.TimeSet(e.Time)
+		return KeyRelease, runCallbacks(xu, e, KeyRelease, e.Event)
+	case xproto.ButtonPressEvent:
+		e := ButtonPressEvent{&event}
+		xu.TimeSet(e.Time)
+		return ButtonPress, runCallbacks(xu, e, ButtonPress, e.Event)
+	case xproto.ButtonReleaseEvent:
+		e := ButtonReleaseEvent{&event}
+		xu.TimeSet(e.Time)
+		return ButtonRelease, runCallbacks(xu, e, ButtonRelease, e.Event)
+	case xproto.MotionNotifyEvent:
+		e := MotionNotifyEvent{&event}
+		xu.TimeSet(e.Time)
+		return MotionNotify, runCallbacks(xu, e, MotionNotify, e.Event)
+	case xproto.EnterNotifyEvent:
+		e := EnterNotifyEvent{&event}
+		xu.TimeSet(e.Time)
+		return EnterNotify, runCallbacks(xu, e, EnterNotify, e.Event)
+	case xproto.LeaveNotifyEvent:
+		e := LeaveNotifyEvent{&event}
+		xu.TimeSet(e.Time)
+		return LeaveNotify, runCallbacks(xu, e, LeaveNotify, e.Event)
+	case xproto.FocusInEvent:
+		e := FocusInEvent{&event}
+		return FocusIn, runCallbacks(xu, e, FocusIn, e.Event)
+	case xproto.FocusOutEvent:
+		e := FocusOutEvent{&event}
+		return FocusOut, runCallbacks(xu, e, FocusOut, e.Event)
+	case xproto.KeymapNotifyEvent:
+		e := KeymapNotifyEvent{&event}
+		return KeymapNotify, runCallbacks(xu, e, KeymapNotify, NoWindow)
+	case xproto.ExposeEvent:
+		e := ExposeEvent{&event}
+		return Expose, runCallbacks(xu, e, Expose, e.Window)
+	case xproto.GraphicsExposureEvent:
+		e := GraphicsExposureEvent{&event}
+		return GraphicsExposure,
 			runCallbacks(xu, e, GraphicsExposure, xproto.Window(e.Drawable))
-		case xproto.NoExposureEvent:
-			e := NoExposureEvent{&event}
+	case xproto.NoExposureEvent:
+		e := NoExposureEvent{&event}
+		return NoExposure,
 			runCallbacks(xu, e, NoExposure, xproto.Window(e.Drawable))
-		case xproto.VisibilityNotifyEvent:
-			e := VisibilityNotifyEvent{&event}
-			runCallbacks(xu, e, VisibilityNotify, e.Window)
-		case xproto.CreateNotifyEvent:
-			e := CreateNotifyEvent{&event}
-			runCallbacks(xu, e, CreateNotify, e.Parent)
-		case xproto.DestroyNotifyEvent:
-			e := DestroyNotifyEvent{&event}
-			runCallbacks(xu, e, DestroyNotify, e.Window)
-		case xproto.UnmapNotifyEvent:
-			e := UnmapNotifyEvent{&event}
-			runCallbacks(xu, e, UnmapNotify, e.Window)
-		case xproto.MapNotifyEvent:
-			e := MapNotifyEvent{&event}
-			runCallbacks(xu, e, MapNotify, e.Event)
-		case xproto.MapRequestEvent:
-			e := MapRequestEvent{&event}
-			runCallbacks(xu, e, MapRequest, e.Window)
-			runCallbacks(xu, e, MapRequest, e.Parent)
-		case xproto.ReparentNotifyEvent:
-			e := ReparentNotifyEvent{&event}
-			runCallbacks(xu, e, ReparentNotify, e.Window)
-		case xproto.ConfigureNotifyEvent:
-			e := ConfigureNotifyEvent{&event}
-			runCallbacks(xu, e, ConfigureNotify, e.Window)
-		case xproto.ConfigureRequestEvent:
-			e := ConfigureRequestEvent{&event}
-			runCallbacks(xu, e, ConfigureRequest, e.Window)
-			runCallbacks(xu, e, ConfigureRequest, e.Parent)
-		case xproto.GravityNotifyEvent:
-			e := GravityNotifyEvent{&event}
-			runCallbacks(xu, e, GravityNotify, e.Window)
-		case xproto.ResizeRequestEvent:
-			e := ResizeRequestEvent{&event}
-			runCallbacks(xu, e, ResizeRequest, e.Window)
-		case xproto.CirculateNotifyEvent:
-			e := CirculateNotifyEvent{&event}
-			runCallbacks(xu, e, CirculateNotify, e.Window)
-		case xproto.CirculateRequestEvent:
-			e := CirculateRequestEvent{&event}
-			runCallbacks(xu, e, CirculateRequest, e.Window)
-		case xproto.PropertyNotifyEvent:
-			e := PropertyNotifyEvent{&event}
+	case xproto.VisibilityNotifyEvent:
+		e := VisibilityNotifyEvent{&event}
+		return VisibilityNotify, runCallbacks(xu, e, VisibilityNotify, e.Window)
+	case xproto.CreateNotifyEvent:
+		e := CreateNotifyEvent{&event}
+		return CreateNotify, runCallbacks(xu, e, CreateNotify, e.Parent)
+	case xproto.DestroyNotifyEvent:
+		e := DestroyNotifyEvent{&event}
+		return DestroyNotify, runCallbacks(xu, e, DestroyNotify, e.Window)
+	case xproto.UnmapNotifyEvent:
+		e := UnmapNotifyEvent{&event}
+		return UnmapNotify, runCallbacks(xu, e, UnmapNotify, e.Window)
+	case xproto.MapNotifyEvent:
+		e := MapNotifyEvent{&event}
+		return MapNotify, runCallbacks(xu, e, MapNotify, e.Event)
+	case xproto.MapRequestEvent:
+		e := MapRequestEvent{&event}
+		n := runCallbacks(xu, e, MapRequest, e.Window)
+		n += runCallbacks(xu, e, MapRequest, e.Parent)
+		return MapRequest, n
+	case xproto.ReparentNotifyEvent:
+		e := ReparentNotifyEvent{&event}
+		return ReparentNotify, runCallbacks(xu, e, ReparentNotify, e.Window)
+	case xproto.ConfigureNotifyEvent:
+		e := ConfigureNotifyEvent{&event}
+		return ConfigureNotify, runCallbacks(xu, e, ConfigureNotify, e.Window)
+	case xproto.ConfigureRequestEvent:
+		e := ConfigureRequestEvent{&event}
+		n := runCallbacks(xu, e, ConfigureRequest, e.Window)
+		n += runCallbacks(xu, e, ConfigureRequest, e.Parent)
+		return ConfigureRequest, n
+	case xproto.GravityNotifyEvent:
+		e := GravityNotifyEvent{&event}
+		return GravityNotify, runCallbacks(xu, e, GravityNotify, e.Window)
+	case xproto.ResizeRequestEvent:
+		e := ResizeRequestEvent{&event}
+		return ResizeRequest, runCallbacks(xu, e, ResizeRequest, e.Window)
+	case xproto.CirculateNotifyEvent:
+		e := CirculateNotifyEvent{&event}
+		return CirculateNotify, runCallbacks(xu, e, CirculateNotify, e.Window)
+	case xproto.CirculateRequestEvent:
+		e := CirculateRequestEvent{&event}
+		return CirculateRequest, runCallbacks(xu, e, CirculateRequest, e.Window)
+	case xproto.PropertyNotifyEvent:
+		e := PropertyNotifyEvent{&event}
+		xu.TimeSet(e.Time)
+		return PropertyNotify, runCallbacks(xu, e, PropertyNotify, e.Window)
+	case xproto.SelectionClearEvent:
+		e := SelectionClearEvent{&event}
+		xu.TimeSet(e.Time)
+		return SelectionClear, runCallbacks(xu, e, SelectionClear, e.Owner)
+	case xproto.SelectionRequestEvent:
+		e := SelectionRequestEvent{&event}
+		if e.Time != xproto.TimeCurrentTime {
 			xu.TimeSet(e.Time)
-			runCallbacks(xu, e, PropertyNotify, e.Window)
-		case xproto.SelectionClearEvent:
-			e := SelectionClearEvent{&event}
-			xu.TimeSet(e.Time)
-			runCallbacks(xu, e, SelectionClear, e.Owner)
-		case xproto.SelectionRequestEvent:
-			e := SelectionRequestEvent{&event}
-			xu.TimeSet(e.Time)
-			runCallbacks(xu, e, SelectionRequest, e.Requestor)
-		case xproto.SelectionNotifyEvent:
-			e := SelectionNotifyEvent{&event}
+		}
+		return SelectionRequest,
+			runCallbacks(xu, e, SelectionRequest, e.Owner)
+	case xproto.SelectionNotifyEvent:
+		e := SelectionNotifyEvent{&event}
+		if e.Time != xproto.TimeCurrentTime {
 			xu.TimeSet(e.Time)
-			runCallbacks(xu, e, SelectionNotify, e.Requestor)
-		case xproto.ColormapNotifyEvent:
-			e := ColormapNotifyEvent{&event}
-			runCallbacks(xu, e, ColormapNotify, e.Window)
-		case xproto.ClientMessageEvent:
-			e := ClientMessageEvent{&event}
-			runCallbacks(xu, e, ClientMessage, e.Window)
-		case xproto.MappingNotifyEvent:
-			e := MappingNotifyEvent{&event}
-			runCallbacks(xu, e, MappingNotify, NoWindow)
-		case shape.NotifyEvent:
-			e := ShapeNotifyEvent{&event}
-			runCallbacks(xu, e, ShapeNotify, e.AffectedWindow)
-		default:
-			if event != nil {
-				xgbutil.Logger.Printf("ERROR: UNSUPPORTED EVENT TYPE: %T",
-					event)
-			}
 		}
-
-	END:
-
-		if pingBefore != nil && pingAfter != nil {
-			pingAfter <- struct{}{}
+		return SelectionNotify,
+			runCallbacks(xu, e, SelectionNotify, e.Requestor)
+	case xproto.ColormapNotifyEvent:
+		e := ColormapNotifyEvent{&event}
+		return ColormapNotify, runCallbacks(xu, e, ColormapNotify, e.Window)
+	case xproto.ClientMessageEvent:
+		e := ClientMessageEvent{&event}
+		return ClientMessage, runCallbacks(xu, e, ClientMessage, e.Window)
+	case xproto.MappingNotifyEvent:
+		e := MappingNotifyEvent{&event}
+		return MappingNotify, runCallbacks(xu, e, MappingNotify, NoWindow)
+	case shape.NotifyEvent:
+		e := ShapeNotifyEvent{&event}
+		return ShapeNotify, runCallbacks(xu, e, ShapeNotify, e.AffectedWindow)
+	case randr.ScreenChangeNotifyEvent, randr.NotifyEvent:
+		// There are no callbacks for RandR events, but hooks can watch for
+		// them. (i.e., xrandr.Watch.) So they are not unsupported.
+		return 0, 0
+	default:
+		if event != nil {
+			xgbutil.Logger.Printf("ERROR: UNSUPPORTED EVENT TYPE: %T",
+				event)
 		}
 	}
+	return 0, 0
 }
@@ -0,0 +1,152 @@
+package xevent_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xevent"
+)
+
+func TestDispatch(t *testing.T) {
+	xu, _, err := xgbutil.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xu.Conn().Close()
+
+	win := xu.RootWin()
+	var pressed []xproto.Keycode
+	xevent.KeyPressFun(
+		func(xu *xgbutil.XUtil, ev xevent.KeyPressEvent) {
+			pressed = append(pressed, ev.Detail)
+		}).Connect(xu, win)
+
+	xevent.Dispatch(xu, xproto.KeyPressEvent{Event: win, Detail: 38})
+	xevent.Dispatch(xu, xproto.KeyPressEvent{Event: win + 1, Detail: 39})
+	xevent.Dispatch(xu, xproto.KeyPressEvent{Event: win, Detail: 40, Time: 7})
+
+	if len(pressed) != 2 || pressed[0] != 38 || pressed[1] != 40 {
+		t.Fatalf("Expected key presses [38 40], but got %v.", pressed)
+	}
+	if xu.TimeGet() != 7 {
+		t.Fatalf("Expected the last event time to be 7, but got %d.",
+			xu.TimeGet())
+	}
+}
+
+func TestDispatchMockEvent(t *testing.T) {
+	xu, m, err := xgbutil.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xu.Conn().Close()
+
+	win := xu.RootWin()
+	var got *xevent.PropertyNotifyEvent
+	xevent.PropertyNotifyFun(
+		func(xu *xgbutil.XUtil, ev xevent.PropertyNotifyEvent) {
+			got = &ev
+		}).Connect(xu, win)
+
+	err = m.SendEvent(xproto.PropertyNotifyEvent{
+		Window: win,
+		Atom:   xproto.AtomWmName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev, xerr := xu.Conn().WaitForEvent()
+	if xerr != nil {
+		t.Fatal(xerr)
+	}
+	xevent.Dispatch(xu, ev)
+
+	if got == nil || got.Atom != xproto.AtomWmName {
+		t.Fatalf("Expected a PropertyNotify event for WM_NAME, but got %v.",
+			got)
+	}
+}
+
+func TestDispatchNoCompression(t *testing.T) {
+	xu, _, err := xgbutil.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xu.Conn().Close()
+
+	win := xu.RootWin()
+	var xs []int16
+	xevent.MotionNotifyFun(
+		func(xu *xgbutil.XUtil, ev xevent.MotionNotifyEvent) {
+			xs = append(xs, ev.EventX)
+		}).Connect(xu, win)
+
+	xevent.MotionCompressionSet(xu, true)
+	xevent.Enqueue(xu, xproto.MotionNotifyEvent{Event: win, EventX: 50}, nil)
+	xevent.Dispatch(xu, xproto.MotionNotifyEvent{Event: win, EventX: 1})
+
+	if len(xs) != 1 || xs[0] != 1 {
+		t.Fatalf("Expected motion at [1], but got %v.", xs)
+	}
+	if xevent.Empty(xu) {
+		t.Fatal("Expected the queued MotionNotify event to be left alone.")
+	}
+}
+
+func TestDetachHookHandle(t *testing.T) {
+	xu, _, err := xgbutil.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xu.Conn().Close()
+
+	runs := 0
+	h := xevent.ConnectHookHandle(xu, xevent.HookFun(
+		func(xu *xgbutil.XUtil, ev interface{}) bool {
+			runs++
+			return true
+		}))
+	if len(xu.Hooks) != 1 {
+		t.Fatalf("Expected 1 hook, but got %d.", len(xu.Hooks))
+	}
+	xevent.Dispatch(xu, xproto.KeyPressEvent{Event: xu.RootWin()})
+	if runs != 1 {
+		t.Fatalf("Expected the hook to run once, but it ran %d times.", runs)
+	}
+
+	xevent.DetachHandle(xu, h)
+	if len(xu.Hooks) != 0 {
+		t.Fatalf("Expected no hooks, but got %d.", len(xu.Hooks))
+	}
+	xevent.Dispatch(xu, xproto.KeyPressEvent{Event: xu.RootWin()})
+	if runs != 1 {
+		t.Fatalf("Expected the detached hook not to run, but it ran %d "+
+			"times.", runs)
+	}
+}
+
+func TestReadAndProcessClosed(t *testing.T) {
+	xu, _, err := xgbutil.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	processed, err := xevent.ReadAndProcess(xu, time.Millisecond)
+	if processed || err != nil {
+		t.Fatalf("Expected nothing to be processed, but got (%v, %v).",
+			processed, err)
+	}
+
+	xu.Conn().Close()
+	processed, err = xevent.ReadAndProcess(xu, time.Millisecond)
+	if processed || err == nil {
+		t.Fatalf("Expected an error after the connection was closed, but "+
+			"got (%v, %v).", processed, err)
+	}
+	if !xevent.Quitting(xu) {
+		t.Fatal("Expected Quit to be called after the connection was closed.")
+	}
+}
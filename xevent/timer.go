@@ -0,0 +1,108 @@
+package xevent
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+)
+
+// timerIds is used to give each Timer a unique identifier, so that the client
+// messages sent by different timers can be told apart.
+var timerIds uint32
+
+// Timer runs a function from inside the main event loop once some amount of
+// time has passed. It is used to implement things like press and hold,
+// timeouts and debouncing without blocking the main event loop.
+//
+// When the timer expires, a client message is sent to the dummy window. The
+// function is run when that client message is processed, like every other
+// callback. So the methods of a Timer should only be called from inside the
+// main event loop (or before it is started).
+type Timer struct {
+	f          func(xu *xgbutil.XUtil)
+	id         int
+	atom       xproto.Atom
+	generation int
+	timer      *time.Timer
+	handle     *Handle
+}
+
+// NewTimer creates a stopped timer that runs 'f' when it expires. Use Reset
+// to start it, and Detach when it is no longer needed.
+func NewTimer(xu *xgbutil.XUtil, f func(xu *xgbutil.XUtil)) (*Timer, error) {
+	name := "_XGBUTIL_TIMER"
+	reply, err := xproto.InternAtom(xu.Conn(), false, uint16(len(name)),
+		name).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("NewTimer: Could not intern atom '%s': %s",
+			name, err)
+	}
+
+	t := &Timer{
+		f:    f,
+		id:   int(atomic.AddUint32(&timerIds, 1)),
+		atom: reply.Atom,
+	}
+	t.handle = ConnectHandle(xu, ClientMessage, xu.Dummy(),
+		ClientMessageFun(func(xu *xgbutil.XUtil, ev ClientMessageEvent) {
+			if t.expired(ev) {
+				t.f(xu)
+			}
+		}))
+	return t, nil
+}
+
+// Reset (re)starts the timer so that it expires after 'd'. If the timer was
+// already running, the previous expiry is cancelled.
+func (t *Timer) Reset(xu *xgbutil.XUtil, d time.Duration) {
+	t.Stop()
+
+	id, gen := t.id, t.generation
+	t.timer = time.AfterFunc(d, func() {
+		cm, err := NewClientMessage(32, xu.Dummy(), t.atom, id, gen)
+		if err != nil {
+			xgbutil.Logger.Printf("Timer: Could not create client "+
+				"message: %s", err)
+			return
+		}
+		xproto.SendEvent(xu.Conn(), false, xu.Dummy(), 0, string(cm.Bytes()))
+	})
+}
+
+// Stop stops the timer. Any client message already sent by it is ignored.
+func (t *Timer) Stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.generation++
+}
+
+// Active returns true if the timer is running.
+func (t *Timer) Active() bool {
+	return t.timer != nil
+}
+
+// Detach stops the timer and removes its client message handler. The timer
+// cannot be used after it has been detached.
+func (t *Timer) Detach(xu *xgbutil.XUtil) {
+	t.Stop()
+	DetachHandle(xu, t.handle)
+}
+
+// expired returns true if the client message was sent by the current timer.
+func (t *Timer) expired(ev ClientMessageEvent) bool {
+	if ev.Type != t.atom || ev.Format != 32 || t.timer == nil {
+		return false
+	}
+	data := ev.Data.Data32
+	if int(data[0]) != t.id || int(data[1]) != t.generation {
+		return false
+	}
+	t.timer = nil
+	return true
+}
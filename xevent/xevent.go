@@ -1,6 +1,9 @@
 package xevent
 
 import (
+	"fmt"
+	"sync"
+
 	"github.com/jezek/xgb"
 	"github.com/jezek/xgb/xproto"
 
@@ -79,6 +82,16 @@ func Empty(xu *xgbutil.XUtil) bool {
 	return len(xu.Evqueue) == 0
 }
 
+// QueueLen returns the number of events/errors currently in the queue.
+// This can be useful to detect when events are arriving faster than they
+// can be processed.
+func QueueLen(xu *xgbutil.XUtil) int {
+	xu.EvqueueLck.RLock()
+	defer xu.EvqueueLck.RUnlock()
+
+	return len(xu.Evqueue)
+}
+
 // Peek returns a *copy* of the current queue so we can examine it.
 // This can be useful when trying to determine if a particular kind of
 // event will be processed in the future.
@@ -107,6 +120,51 @@ func ErrorHandlerGet(xu *xgbutil.XUtil) xgbutil.ErrorHandlerFun {
 	return xu.ErrorHandler
 }
 
+// FilterSet sets the event filter, which is run on every event in the main
+// event loop after it has been dequeued but before any hooks or callbacks are
+// run. When the filter returns false, the event is discarded.
+// Passing nil removes the filter.
+func FilterSet(xu *xgbutil.XUtil, fun xgbutil.EventFilterFun) {
+	xu.EventFilter = fun
+}
+
+// FilterGet retrieves the event filter. It returns nil if there is none.
+func FilterGet(xu *xgbutil.XUtil) xgbutil.EventFilterFun {
+	return xu.EventFilter
+}
+
+// AfterDispatchSet sets a function that is run after all of the callbacks for
+// an event in the main event loop have been run. It is given the type of the
+// event, the number of callbacks run and the time taken to run the hooks and
+// callbacks. This is useful for metrics and tracing.
+// It is not run for events discarded by the event filter or a hook.
+// Passing nil removes it.
+func AfterDispatchSet(xu *xgbutil.XUtil, fun xgbutil.AfterDispatchFun) {
+	xu.AfterDispatch = fun
+}
+
+// AfterDispatchGet retrieves the function set with AfterDispatchSet.
+// It returns nil if there is none.
+func AfterDispatchGet(xu *xgbutil.XUtil) xgbutil.AfterDispatchFun {
+	return xu.AfterDispatch
+}
+
+// MotionCompressionSet sets whether MotionNotify events are compressed in
+// the main event loop. When enabled, a MotionNotify event is skipped if it
+// is immediately followed in the queue by another MotionNotify event for the
+// same window (with the same state). Only the latest one is dispatched.
+// This is useful to avoid doing work for stale pointer positions, like when
+// a window is being dragged.
+// Motion compression is disabled by default.
+func MotionCompressionSet(xu *xgbutil.XUtil, compress bool) {
+	xu.MotionCompression = compress
+}
+
+// MotionCompressionGet returns whether MotionNotify events are compressed.
+func MotionCompressionGet(xu *xgbutil.XUtil) bool {
+	return xu.MotionCompression
+}
+
 type HookFun func(xu *xgbutil.XUtil, event interface{}) bool
 
 func (callback HookFun) Connect(xu *xgbutil.XUtil) {
@@ -147,8 +205,10 @@ func RedirectKeyGet(xu *xgbutil.XUtil) xproto.Window {
 }
 
 // Quit elegantly exits out of the main event loop.
-// "Elegantly" in this case means that it finishes processing the current
-// event, and breaks out of the loop afterwards.
+// "Elegantly" in this case means that the callback currently running (if any)
+// is allowed to return, and then the loop is exited. No further callbacks
+// or hooks are run, even if other callbacks are attached to the current
+// event. Events left in the queue are not processed.
 // There is no particular reason to use this instead of something like os.Exit
 // other than you might have code to run after the main event loop exits to
 // "clean up."
@@ -156,41 +216,87 @@ func Quit(xu *xgbutil.XUtil) {
 	xu.Quit = true
 }
 
+// QuitAfterFlush is the same as Quit, except it first waits for the X server
+// to process every request that has been issued so far. This guarantees that
+// requests made before QuitAfterFlush (i.e., in the current callback) have
+// reached the X server by the time the main event loop exits.
+func QuitAfterFlush(xu *xgbutil.XUtil) {
+	xu.Sync()
+	Quit(xu)
+}
+
 // Quitting returns whether it's time to quit.
 // This is only used in the main event loop in xevent.
 func Quitting(xu *xgbutil.XUtil) bool {
 	return xu.Quit
 }
 
+// StopPropagation can be called from inside a callback to prevent any
+// further callbacks attached to the same (event, window) tuple from running
+// for the current event.
+// Callbacks are run in order of priority (see the ConnectPriority methods),
+// so a callback with a high priority can use StopPropagation to capture
+// events before callbacks with a lower priority see them.
+// It only affects the event whose callbacks are being run by the calling
+// goroutine. Like the callbacks themselves, it must not be called from any
+// other goroutine.
+func StopPropagation(xu *xgbutil.XUtil) {
+	xu.CallbacksStopped = true
+}
+
 // attachCallback associates a (event, window) tuple with an event.
+// The callback has a priority of 0.
+func attachCallback(xu *xgbutil.XUtil, evtype int, win xproto.Window,
+	fun xgbutil.Callback) {
+
+	attachCallbackPriority(xu, evtype, win, 0, fun)
+}
+
+// attachCallbackPriority associates a (event, window) tuple with an event.
+// Callbacks with a higher priority are run first. Callbacks with the same
+// priority are run in the order in which they were attached.
 // Use copy on write since we run callbacks *a lot* more than attaching them.
 // (The copy on write only applies to the slice of callbacks rather than
 // the map itself, since the initial allocation is guaranteed to come before
 // any use of it.)
-func attachCallback(xu *xgbutil.XUtil, evtype int, win xproto.Window,
-	fun xgbutil.Callback) {
+func attachCallbackPriority(xu *xgbutil.XUtil, evtype int, win xproto.Window,
+	priority int, fun xgbutil.Callback) {
 
 	xu.CallbacksLck.Lock()
 	defer xu.CallbacksLck.Unlock()
 
 	if _, ok := xu.Callbacks[evtype]; !ok {
 		xu.Callbacks[evtype] = make(map[xproto.Window][]xgbutil.Callback, 20)
+		xu.Priorities[evtype] = make(map[xproto.Window][]int, 20)
 	}
-	if _, ok := xu.Callbacks[evtype][win]; !ok {
-		xu.Callbacks[evtype][win] = make([]xgbutil.Callback, 0)
+	cbs := xu.Callbacks[evtype][win]
+	prios := xu.Priorities[evtype][win]
+
+	// Find the last spot with a priority at least as high as this one.
+	i := len(prios)
+	for i > 0 && prios[i-1] < priority {
+		i--
 	}
 
 	// COW
-	newCallbacks := make([]xgbutil.Callback, len(xu.Callbacks[evtype][win]))
-	copy(newCallbacks, xu.Callbacks[evtype][win])
+	newCallbacks := make([]xgbutil.Callback, 0, len(cbs)+1)
+	newCallbacks = append(newCallbacks, cbs[:i]...)
 	newCallbacks = append(newCallbacks, fun)
+	newCallbacks = append(newCallbacks, cbs[i:]...)
 	xu.Callbacks[evtype][win] = newCallbacks
+
+	newPrios := make([]int, 0, len(prios)+1)
+	newPrios = append(newPrios, prios[:i]...)
+	newPrios = append(newPrios, priority)
+	newPrios = append(newPrios, prios[i:]...)
+	xu.Priorities[evtype][win] = newPrios
 }
 
 // runCallbacks executes every callback corresponding to a
-// particular event/window tuple.
+// particular event/window tuple, and returns the number of callbacks run.
+// Callbacks stop running early if StopPropagation or Quit is called.
 func runCallbacks(xu *xgbutil.XUtil, event interface{}, evtype int,
-	win xproto.Window) {
+	win xproto.Window) int {
 
 	// The callback slice for a particular (event type, window) tuple uses
 	// copy on write. So just take a pointer to whatever is there and use that.
@@ -199,9 +305,20 @@ func runCallbacks(xu *xgbutil.XUtil, event interface{}, evtype int,
 	cbs := xu.Callbacks[evtype][win]
 	xu.CallbacksLck.RUnlock()
 
-	for _, cb := range cbs {
+	// The stop state belongs to this call. It is restored afterwards, so
+	// that an event dispatched from inside a callback (i.e., with Dispatch)
+	// can neither stop nor be stopped by the callbacks of the outer event.
+	stopped := xu.CallbacksStopped
+	defer func() { xu.CallbacksStopped = stopped }()
+
+	xu.CallbacksStopped = false
+	for i, cb := range cbs {
 		cb.Run(xu, event)
+		if xu.CallbacksStopped || Quitting(xu) {
+			return i + 1
+		}
 	}
+	return len(cbs)
 }
 
 // Detach removes all callbacks associated with a particular window.
@@ -212,6 +329,8 @@ func runCallbacks(xu *xgbutil.XUtil, event interface{}, evtype int,
 //	mousebind.Detach(XUtilValue, window-id)
 //	xevent.Detach(XUtilValue, window-id)
 //
+// DetachWindow does all of this in one call.
+//
 // If a window is no longer receiving events, these methods should be called.
 // Otherwise, the memory used to store the handler info for that window will
 // never be released.
@@ -221,9 +340,177 @@ func Detach(xu *xgbutil.XUtil, win xproto.Window) {
 
 	for evtype, _ := range xu.Callbacks {
 		delete(xu.Callbacks[evtype], win)
+		delete(xu.Priorities[evtype], win)
+	}
+}
+
+// Handle identifies a single callback or hook attached with ConnectHandle or
+// ConnectHookHandle. It can be removed with DetachHandle without removing
+// any other callbacks attached to the same window.
+type Handle struct {
+	evtype int
+	win    xproto.Window
+	cb     *handleCallback
+	hook   *handleHook
+}
+
+// handleCallback and handleHook wrap a callback or hook so that it can be
+// found again. (Functions can't be compared, but pointers can.)
+type handleCallback struct{ xgbutil.Callback }
+type handleHook struct{ xgbutil.CallbackHook }
+
+// ConnectHandle attaches a callback to the (event type, window) tuple, just
+// like its Connect method would, and returns a Handle to detach it with.
+// 'evtype' must be the event type of the callback. For example:
+//
+//	h := xevent.ConnectHandle(XUtilValue, xevent.KeyPress, win,
+//		xevent.KeyPressFun(func(X *xgbutil.XUtil, ev xevent.KeyPressEvent) {
+//			// ...
+//		}))
+//	xevent.DetachHandle(XUtilValue, h)
+func ConnectHandle(xu *xgbutil.XUtil, evtype int, win xproto.Window,
+	cb xgbutil.Callback) *Handle {
+
+	h := &Handle{evtype: evtype, win: win, cb: &handleCallback{cb}}
+	attachCallback(xu, evtype, win, h.cb)
+	return h
+}
+
+// ConnectHookHandle is the same as ConnectHandle, except it connects a hook.
+func ConnectHookHandle(xu *xgbutil.XUtil, hook xgbutil.CallbackHook) *Handle {
+	h := &Handle{hook: &handleHook{hook}}
+
+	// The wrapper itself is added to the hooks, rather than calling its
+	// Connect method, which would add the wrapped hook instead.
+	xu.HooksLck.Lock()
+	defer xu.HooksLck.Unlock()
+
+	// COW
+	newHooks := make([]xgbutil.CallbackHook, len(xu.Hooks))
+	copy(newHooks, xu.Hooks)
+	newHooks = append(newHooks, xgbutil.CallbackHook(h.hook))
+
+	xu.Hooks = newHooks
+	return h
+}
+
+// DetachHandle removes the callback or hook identified by 'h'. It does
+// nothing if it has already been removed (i.e., by Detach).
+func DetachHandle(xu *xgbutil.XUtil, h *Handle) {
+	if h == nil {
+		return
+	}
+	if h.hook != nil {
+		detachHook(xu, h.hook)
+		return
+	}
+
+	xu.CallbacksLck.Lock()
+	defer xu.CallbacksLck.Unlock()
+
+	cbs := xu.Callbacks[h.evtype][h.win]
+	prios := xu.Priorities[h.evtype][h.win]
+	for i, cb := range cbs {
+		if cb != xgbutil.Callback(h.cb) {
+			continue
+		}
+
+		// COW
+		newCallbacks := make([]xgbutil.Callback, 0, len(cbs)-1)
+		newCallbacks = append(newCallbacks, cbs[:i]...)
+		newCallbacks = append(newCallbacks, cbs[i+1:]...)
+		newPrios := make([]int, 0, len(prios)-1)
+		newPrios = append(newPrios, prios[:i]...)
+		newPrios = append(newPrios, prios[i+1:]...)
+		if len(newCallbacks) == 0 {
+			delete(xu.Callbacks[h.evtype], h.win)
+			delete(xu.Priorities[h.evtype], h.win)
+		} else {
+			xu.Callbacks[h.evtype][h.win] = newCallbacks
+			xu.Priorities[h.evtype][h.win] = newPrios
+		}
+		return
 	}
 }
 
+// detachHook removes a hook connected with ConnectHookHandle.
+func detachHook(xu *xgbutil.XUtil, hook *handleHook) {
+	xu.HooksLck.Lock()
+	defer xu.HooksLck.Unlock()
+
+	for i, h := range xu.Hooks {
+		if h != xgbutil.CallbackHook(hook) {
+			continue
+		}
+
+		// COW
+		newHooks := make([]xgbutil.CallbackHook, 0, len(xu.Hooks)-1)
+		newHooks = append(newHooks, xu.Hooks[:i]...)
+		newHooks = append(newHooks, xu.Hooks[i+1:]...)
+		xu.Hooks = newHooks
+		return
+	}
+}
+
+// DetachFun is the type of function registered with DetachHook. It should
+// remove all state associated with 'win', including any grabs.
+type DetachFun func(xu *xgbutil.XUtil, win xproto.Window)
+
+var (
+	detachHooks    []DetachFun
+	detachHooksLck sync.Mutex
+)
+
+// DetachHook registers a function that DetachWindow runs before removing the
+// callbacks of a window. The keybind and mousebind packages use this to
+// release their grabs and handlers, so there is no need to call it unless
+// another package keeps its own per-window state.
+func DetachHook(f DetachFun) {
+	detachHooksLck.Lock()
+	defer detachHooksLck.Unlock()
+
+	detachHooks = append(detachHooks, f)
+}
+
+// DetachWindow removes everything associated with a window in one call. It
+// runs every function registered with DetachHook (which includes
+// keybind.Detach and mousebind.Detach when those packages are imported, so
+// that their grabs are released too), and then calls Detach.
+// This is typically called when a DestroyNotify event is received.
+func DetachWindow(xu *xgbutil.XUtil, win xproto.Window) {
+	detachHooksLck.Lock()
+	hooks := make([]DetachFun, len(detachHooks))
+	copy(hooks, detachHooks)
+	detachHooksLck.Unlock()
+
+	for _, f := range hooks {
+		f(xu, win)
+	}
+	Detach(xu, win)
+}
+
+// SelectInputAdd adds the events in 'mask' (i.e.,
+// xproto.EventMaskPropertyChange) to the events this client has already
+// selected on the window provided. Unlike setting the event mask directly,
+// events selected elsewhere in the program are kept.
+func SelectInputAdd(xu *xgbutil.XUtil, win xproto.Window, mask uint32) error {
+	attrs, err := xproto.GetWindowAttributes(xu.Conn(), win).Reply()
+	if err != nil {
+		return fmt.Errorf("SelectInputAdd: Could not get attributes of "+
+			"window %x: %s", win, err)
+	}
+	if attrs.YourEventMask&mask == mask {
+		return nil
+	}
+	err = xproto.ChangeWindowAttributesChecked(xu.Conn(), win,
+		xproto.CwEventMask, []uint32{attrs.YourEventMask | mask}).Check()
+	if err != nil {
+		return fmt.Errorf("SelectInputAdd: Could not select events on "+
+			"window %x: %s", win, err)
+	}
+	return nil
+}
+
 // SendRootEvent takes a type implementing the xgb.Event interface, converts it
 // to raw X bytes, and sends it to the root window using the SendEvent request.
 func SendRootEvent(xu *xgbutil.XUtil, ev xgb.Event, evMask uint32) error {
@@ -235,3 +522,12 @@ func SendRootEvent(xu *xgbutil.XUtil, ev xgb.Event, evMask uint32) error {
 func ReplayPointer(xu *xgbutil.XUtil) {
 	xproto.AllowEvents(xu.Conn(), xproto.AllowReplayPointer, 0)
 }
+
+// FlushPointer is a quick alias to AllowEvents with 'AsyncPointer' mode,
+// using the time of the most recent event seen. (See XUtil.TimeGet.)
+// This releases the pointer from a synchronous grab so that pointer events
+// are processed normally again, without replaying the event that activated
+// the grab. (Use ReplayPointer to replay it instead.)
+func FlushPointer(xu *xgbutil.XUtil) {
+	xproto.AllowEvents(xu.Conn(), xproto.AllowAsyncPointer, xu.TimeGet())
+}
@@ -3,6 +3,7 @@ package xgbutil
 import (
 	"log"
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/jezek/xgb"
@@ -11,8 +12,28 @@ import (
 )
 
 // Logger is used through xgbutil when messages need to be emitted to stderr.
+// Messages can be sent elsewhere by replacing Logger, or by changing its
+// output. To handle each message with your own function (i.e., to use a
+// structured logger), use a LogFun:
+//
+//	xgbutil.Logger = log.New(xgbutil.LogFun(myLogFunc), "", 0)
+//
+// Errors from unchecked requests that reach the main event loop are handled
+// separately, by the function set with xevent.ErrorHandlerSet. (Which uses
+// Logger by default.)
 var Logger = log.New(os.Stderr, "[xgbutil] ", log.Lshortfile)
 
+// LogFun is an io.Writer that runs the function with each message written to
+// it, without the trailing newline. It is meant to be used as the output of
+// Logger.
+type LogFun func(msg string)
+
+// Write runs the function with 'p' as the message. It never fails.
+func (f LogFun) Write(p []byte) (int, error) {
+	f(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
 // The current maximum request size. I think we can expand this with
 // BigReq, but it probably isn't worth it at the moment.
 const MaxReqSize = (1 << 16) * 4
@@ -28,6 +49,10 @@ type XUtil struct {
 	// conn is the XGB connection object used to issue protocol requests.
 	conn *xgb.Conn
 
+	// display is the display that conn was connected to, which is used by
+	// Reconnect. It is empty if the DISPLAY environment variable was used.
+	display string
+
 	// Quit can be set to true, and the main event loop will finish processing
 	// the current event, and gracefully quit afterwards.
 	// This is exported for use in the xevent package. Please us xevent.Quit
@@ -71,6 +96,18 @@ type XUtil struct {
 	Callbacks    map[int]map[xproto.Window][]Callback
 	CallbacksLck *sync.RWMutex
 
+	// Priorities stores the priority of each callback in Callbacks, at the
+	// same index. It is protected by CallbacksLck.
+	// It is exported for use in the xevent package. Do not use it.
+	Priorities map[int]map[xproto.Window][]int
+
+	// CallbacksStopped is set when a callback asks that no more callbacks be
+	// run for the current event. It is saved and restored around each run of
+	// callbacks, so that nested dispatches don't clobber it.
+	// It is exported for use in the xevent package. Please use
+	// xevent.StopPropagation.
+	CallbacksStopped bool
+
 	// Hooks are called by the XEvent main loop before processing the event
 	// itself. These are meant for instances when it's not possible / easy
 	// to use the normal Hook system. You should not modify this yourself.
@@ -141,6 +178,12 @@ type XUtil struct {
 	// It is exported for use in the mousebind package. Do not use it.
 	Mousegrabs map[MouseKey]int
 
+	// MouseIgnoreMods stores the modifier masks to ignore for mouse bindings
+	// that were connected with their own masks rather than with the global
+	// xevent.IgnoreMods. It is protected by MousebindsLck.
+	// It is exported for use in the mousebind package. Do not use it.
+	MouseIgnoreMods map[MouseKey][]uint16
+
 	// InMouseDrag is true if a drag is currently in progress.
 	// It is exported for use in the mousebind package. Do not use it.
 	InMouseDrag bool
@@ -171,6 +214,39 @@ type XUtil struct {
 	// It is exported for use in the xevent package. To set the default error
 	// handler, please use xevent.ErrorHandlerSet.
 	ErrorHandler ErrorHandlerFun
+
+	// EventFilter is run on every event *in the event loop* before any hooks
+	// or callbacks. If it returns false, the event is discarded. It may be
+	// nil, in which case no events are discarded.
+	// It is exported for use in the xevent package. To set the event filter,
+	// please use xevent.FilterSet.
+	EventFilter EventFilterFun
+
+	// AfterDispatch is run on every event *in the event loop* after all of its
+	// callbacks have been run. It may be nil.
+	// It is exported for use in the xevent package. To set it, please use
+	// xevent.AfterDispatchSet.
+	AfterDispatch AfterDispatchFun
+
+	// MotionCompression is whether consecutive MotionNotify events are
+	// compressed *in the event loop*. It is false by default.
+	// It is exported for use in the xevent package. To set it, please use
+	// xevent.MotionCompressionSet.
+	MotionCompression bool
+
+	// StackingCache is the last _NET_CLIENT_LIST_STACKING seen by
+	// ewmh.ClientListStackingWatch. It is protected by StackingCacheLck.
+	// It is exported for use in the ewmh package. Do not use it.
+	StackingCache []xproto.Window
+
+	// StackingCached is true once ewmh.ClientListStackingWatch has filled
+	// StackingCache. It is protected by StackingCacheLck.
+	// It is exported for use in the ewmh package. Do not use it.
+	StackingCached bool
+
+	// StackingCacheLck protects StackingCache and StackingCached.
+	// It is exported for use in the ewmh package. Do not use it.
+	StackingCacheLck *sync.RWMutex
 }
 
 // NewConn connects to the X server using the DISPLAY environment variable
@@ -195,8 +271,12 @@ func NewConnDisplay(display string) (*XUtil, error) {
 		return nil, err
 	}
 
-	return NewConnXgb(c)
-
+	xu, err := NewConnXgb(c)
+	if err != nil {
+		return nil, err
+	}
+	xu.display = display
+	return xu, nil
 }
 
 // NewConnXgb use the specific xgb.Conn to create a new XUtil.
@@ -222,6 +302,7 @@ func NewConnXgb(c *xgb.Conn) (*XUtil, error) {
 		AtomNamesLck:     &sync.RWMutex{},
 		Callbacks:        make(map[int]map[xproto.Window][]Callback, 33),
 		CallbacksLck:     &sync.RWMutex{},
+		Priorities:       make(map[int]map[xproto.Window][]int, 33),
 		Hooks:            make([]CallbackHook, 0),
 		HooksLck:         &sync.RWMutex{},
 		Keymap:           nil, // we don't have anything yet
@@ -234,17 +315,30 @@ func NewConnXgb(c *xgb.Conn) (*XUtil, error) {
 		Mousebinds:       make(map[MouseKey][]CallbackMouse, 10),
 		MousebindsLck:    &sync.RWMutex{},
 		Mousegrabs:       make(map[MouseKey]int, 10),
+		MouseIgnoreMods:  make(map[MouseKey][]uint16),
 		InMouseDrag:      false,
 		MouseDragStepFun: nil,
 		MouseDragEndFun:  nil,
+		StackingCacheLck: &sync.RWMutex{},
 		ErrorHandler:     func(err xgb.Error) { Logger.Println(err) },
+		EventFilter:      nil,
+		AfterDispatch:    nil,
 	}
 
+	if err := xu.createResources(); err != nil {
+		return nil, err
+	}
+	return xu, nil
+}
+
+// createResources creates the graphics context and dummy window of an XUtil,
+// and initializes the Xinerama extension.
+func (xu *XUtil) createResources() error {
 	var err error = nil
 	// Create a general purpose graphics context
 	xu.gc, err = xproto.NewGcontextId(xu.conn)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	xproto.CreateGC(xu.conn, xu.gc, xproto.Drawable(xu.root),
 		xproto.GcForeground, []uint32{xu.screen.WhitePixel})
@@ -252,7 +346,7 @@ func NewConnXgb(c *xgb.Conn) (*XUtil, error) {
 	// Create a dummy window
 	xu.dummy, err = xproto.NewWindowId(xu.conn)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	xproto.CreateWindow(xu.conn, xu.Screen().RootDepth, xu.dummy, xu.RootWin(),
 		-1000, -1000, 1, 1, 0,
@@ -272,7 +366,127 @@ func NewConnXgb(c *xgb.Conn) (*XUtil, error) {
 			"because the XINERAMA extension could not be loaded.")
 	}
 
-	return xu, nil
+	return nil
+}
+
+// Reconnect replaces a connection to the X server that has been lost (i.e.,
+// because the X server was restarted) with a new connection to the same
+// display, so that a long running program doesn't need to exit.
+// The atoms in the atom cache are interned again, since their identifiers
+// are different on the new connection, and a new graphics context and dummy
+// window are created. All event callbacks, hooks, key and mouse bindings are
+// forgotten, since the windows they were attached to and the grabs they
+// relied on were lost with the old connection. (Window identifiers are
+// recycled, so keeping them would run callbacks for unrelated windows.) The
+// keyboard and modifier mappings are forgotten too.
+// Everything else that was set up on the old connection, like event masks
+// and windows, is gone as well.
+// 'setup', if not nil, is run after the new connection is made so that it
+// can all be set up again. (i.e., calling keybind.Initialize and connecting
+// key bindings.) Any error it returns is returned by Reconnect.
+//
+// The main event loop stops when the connection is lost, so a program could
+// do something like:
+//
+//	for {
+//		xevent.Main(X)
+//		if err := X.Reconnect(setup); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// Reconnect must not be called while the main event loop is running.
+func (xu *XUtil) Reconnect(setup func(xu *XUtil) error) error {
+	c, err := xgb.NewConnDisplay(xu.display)
+	if err != nil {
+		return err
+	}
+	xu.conn.Close()
+
+	xu.conn = c
+	xu.setup = xproto.Setup(c)
+	xu.screen = xu.setup.DefaultScreen(c)
+	xu.root = xu.screen.Root
+	xu.Quit = false
+	xu.eventTime = 0
+
+	xu.EvqueueLck.Lock()
+	xu.Evqueue = xu.Evqueue[:0]
+	xu.EvqueueLck.Unlock()
+
+	if err := xu.reinternAtoms(); err != nil {
+		return err
+	}
+
+	xu.CallbacksLck.Lock()
+	xu.Callbacks = make(map[int]map[xproto.Window][]Callback, 33)
+	xu.Priorities = make(map[int]map[xproto.Window][]int, 33)
+	xu.CallbacksLck.Unlock()
+
+	xu.HooksLck.Lock()
+	xu.Hooks = make([]CallbackHook, 0)
+	xu.HooksLck.Unlock()
+
+	xu.Keymap = nil
+	xu.Modmap = nil
+	xu.KeyRedirect = 0
+
+	xu.StackingCacheLck.Lock()
+	xu.StackingCache = nil
+	xu.StackingCached = false
+	xu.StackingCacheLck.Unlock()
+
+	xu.KeybindsLck.Lock()
+	xu.Keybinds = make(map[KeyKey][]CallbackKey, 10)
+	xu.Keygrabs = make(map[KeyKey]int, 10)
+	xu.Keystrings = make([]KeyString, 0, 10)
+	xu.KeybindsLck.Unlock()
+
+	xu.MousebindsLck.Lock()
+	xu.Mousebinds = make(map[MouseKey][]CallbackMouse, 10)
+	xu.Mousegrabs = make(map[MouseKey]int, 10)
+	xu.MouseIgnoreMods = make(map[MouseKey][]uint16)
+	xu.MousebindsLck.Unlock()
+	xu.InMouseDrag = false
+	xu.MouseDragStepFun = nil
+	xu.MouseDragEndFun = nil
+
+	if err := xu.createResources(); err != nil {
+		return err
+	}
+	if setup != nil {
+		return setup(xu)
+	}
+	return nil
+}
+
+// reinternAtoms interns every atom in the atom cache on the current
+// connection, and rebuilds both atom caches with the new identifiers.
+func (xu *XUtil) reinternAtoms() error {
+	xu.AtomsLck.Lock()
+	defer xu.AtomsLck.Unlock()
+	xu.AtomNamesLck.Lock()
+	defer xu.AtomNamesLck.Unlock()
+
+	names := make([]string, 0, len(xu.Atoms))
+	cookies := make([]xproto.InternAtomCookie, 0, len(xu.Atoms))
+	for name := range xu.Atoms {
+		names = append(names, name)
+		cookies = append(cookies, xproto.InternAtom(xu.conn, false,
+			uint16(len(name)), name))
+	}
+
+	xu.Atoms = make(map[string]xproto.Atom, len(names))
+	xu.AtomNames = make(map[xproto.Atom]string, len(names))
+	for i, cookie := range cookies {
+		reply, err := cookie.Reply()
+		if err != nil {
+			return err
+		}
+		xu.Atoms[names[i]] = reply.Atom
+		xu.AtomNames[reply.Atom] = names[i]
+	}
+	return nil
 }
 
 // Conn returns the xgb connection object.
@@ -317,11 +531,18 @@ func (xu *XUtil) RootWinSet(root xproto.Window) {
 }
 
 // TimeGet gets the most recent time seen by an event.
+// The main event loop records the time of every key, button, motion,
+// crossing, property and selection event. Requests that take a timestamp,
+// like SetInputFocus, SetSelectionOwner and ConvertSelection, should use
+// this instead of CurrentTime, as the ICCCM requires. Otherwise, requests
+// made in response to old events can win against newer ones, which is a
+// common cause of focus stealing.
 func (xu *XUtil) TimeGet() xproto.Timestamp {
 	return xu.eventTime
 }
 
 // TimeSet sets the most recent time seen by an event.
+// This is only necessary if events are read outside of the main event loop.
 func (xu *XUtil) TimeSet(t xproto.Timestamp) {
 	xu.eventTime = t
 }
@@ -0,0 +1,90 @@
+package xgraphics
+
+/*
+xgraphics/composite.go contains Composite, which blends a stack of images into
+a destination image in a single pass.
+*/
+
+import (
+	"image"
+	"math"
+)
+
+// Layer is an image drawn by Composite. The top-left corner of Image is drawn
+// at Point in the destination, and Opacity (in the range [0, 1]) is applied
+// on top of the image's own alpha channel.
+type Layer struct {
+	Image   *Image
+	Point   image.Point
+	Opacity float64
+}
+
+// Composite alpha blends each layer into 'dst', from the first (bottom) layer
+// to the last (top) layer. Parts of layers that fall outside of 'dst' are
+// ignored.
+// The pixels of all images are taken to have premultiplied alpha (like the
+// icons made by NewEwmhIcon), and the alpha channel of 'dst' is blended too,
+// so the result can itself be used as a layer.
+// This is more efficient than calling Blend for each layer, since each pixel
+// of 'dst' is only read and written once. (The layers are blended with full
+// precision in between, too.)
+func Composite(dst *Image, layers []Layer) {
+	// placed is a layer clipped to the destination.
+	type placed struct {
+		src     *Image
+		rect    image.Rectangle
+		offset  image.Point
+		opacity float64
+	}
+
+	var places []placed
+	var bounds image.Rectangle
+	for _, layer := range layers {
+		if layer.Image == nil || layer.Opacity <= 0 {
+			continue
+		}
+		src := layer.Image
+		r := src.Rect.Sub(src.Rect.Min).Add(layer.Point).Intersect(dst.Rect)
+		if r.Empty() {
+			continue
+		}
+		places = append(places, placed{
+			src:     src,
+			rect:    r,
+			offset:  src.Rect.Min.Sub(layer.Point),
+			opacity: math.Min(layer.Opacity, 1),
+		})
+		bounds = bounds.Union(r)
+	}
+
+	var px [4]float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			i := dst.PixOffset(x, y)
+			for k := range px {
+				px[k] = float64(dst.Pix[i+k])
+			}
+
+			covered := false
+			for _, p := range places {
+				if !(image.Point{x, y}).In(p.rect) {
+					continue
+				}
+				covered = true
+
+				j := p.src.PixOffset(x+p.offset.X, y+p.offset.Y)
+				inv := 1 - float64(p.src.Pix[j+3])*p.opacity/0xff
+				for k := range px {
+					px[k] = float64(p.src.Pix[j+k])*p.opacity + px[k]*inv
+				}
+			}
+			if !covered {
+				continue
+			}
+
+			for k, v := range px {
+				dst.Pix[i+k] = uint8(math.Min(v+0.5, 0xff))
+			}
+		}
+	}
+}
@@ -54,16 +54,20 @@ func convertYCbCr(dest *Image, src *image.YCbCr) {
 }
 
 func convertRGBA(dest *Image, src *image.RGBA) {
-	var x, y, i, si int
-
-	for x = dest.Rect.Min.X; x < dest.Rect.Max.X; x++ {
-		for y = dest.Rect.Min.Y; y < dest.Rect.Max.Y; y++ {
-			si = src.PixOffset(x, y)
-			i = dest.PixOffset(x, y)
-			dest.Pix[i+0] = src.Pix[si+2]
-			dest.Pix[i+1] = src.Pix[si+1]
-			dest.Pix[i+2] = src.Pix[si+0]
-			dest.Pix[i+3] = src.Pix[si+3]
+	var y, i, si int
+	width := 4 * dest.Rect.Dx()
+
+	// Rows are converted with a single slice each, so that the inner loop
+	// doesn't need to compute any offsets.
+	for y = dest.Rect.Min.Y; y < dest.Rect.Max.Y; y++ {
+		si = src.PixOffset(dest.Rect.Min.X, y)
+		i = dest.PixOffset(dest.Rect.Min.X, y)
+		srow, drow := src.Pix[si:si+width], dest.Pix[i:i+width]
+		for i = 0; i < width; i += 4 {
+			drow[i+0] = srow[i+2]
+			drow[i+1] = srow[i+1]
+			drow[i+2] = srow[i+0]
+			drow[i+3] = srow[i+3]
 		}
 	}
 }
@@ -84,19 +88,20 @@ func convertRGBA64(dest *Image, src *image.RGBA64) {
 }
 
 func convertNRGBA(dest *Image, src *image.NRGBA) {
-	var x, y, i, si int
+	var y, i, si int
 	var a uint16
-
-	for x = dest.Rect.Min.X; x < dest.Rect.Max.X; x++ {
-		for y = dest.Rect.Min.Y; y < dest.Rect.Max.Y; y++ {
-			si = src.PixOffset(x, y)
-			i = dest.PixOffset(x, y)
-			a = uint16(src.Pix[si+3])
-
-			dest.Pix[i+0] = uint8((uint16(src.Pix[si+2]) * a) / 0xff)
-			dest.Pix[i+1] = uint8((uint16(src.Pix[si+1]) * a) / 0xff)
-			dest.Pix[i+2] = uint8((uint16(src.Pix[si+0]) * a) / 0xff)
-			dest.Pix[i+3] = src.Pix[si+3]
+	width := 4 * dest.Rect.Dx()
+
+	for y = dest.Rect.Min.Y; y < dest.Rect.Max.Y; y++ {
+		si = src.PixOffset(dest.Rect.Min.X, y)
+		i = dest.PixOffset(dest.Rect.Min.X, y)
+		srow, drow := src.Pix[si:si+width], dest.Pix[i:i+width]
+		for i = 0; i < width; i += 4 {
+			a = uint16(srow[i+3])
+			drow[i+0] = uint8((uint16(srow[i+2]) * a) / 0xff)
+			drow[i+1] = uint8((uint16(srow[i+1]) * a) / 0xff)
+			drow[i+2] = uint8((uint16(srow[i+0]) * a) / 0xff)
+			drow[i+3] = srow[i+3]
 		}
 	}
 }
@@ -0,0 +1,56 @@
+package xgraphics
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// benchRect is the size of the images converted by the benchmarks.
+var benchRect = image.Rect(0, 0, 512, 512)
+
+// newBenchImage returns a destination image for the benchmarks. It isn't
+// connected to an X server.
+func newBenchImage() *Image {
+	return &Image{
+		Pix:    make([]uint8, 4*benchRect.Dx()*benchRect.Dy()),
+		Stride: 4 * benchRect.Dx(),
+		Rect:   benchRect,
+	}
+}
+
+// newBenchRGBA returns a source image for the benchmarks, filled with a
+// gradient so that every pixel is different.
+func newBenchRGBA() *image.RGBA {
+	src := image.NewRGBA(benchRect)
+	for y := benchRect.Min.Y; y < benchRect.Max.Y; y++ {
+		for x := benchRect.Min.X; x < benchRect.Max.X; x++ {
+			src.SetRGBA(x, y, color.RGBA{uint8(x), uint8(y), 0x80, 0xff})
+		}
+	}
+	return src
+}
+
+// genericImage hides the concrete type of an image, so that the generic
+// (slow) conversion is used.
+type genericImage struct {
+	image.Image
+}
+
+func BenchmarkConvertRGBA(b *testing.B) {
+	dest, src := newBenchImage(), newBenchRGBA()
+	b.SetBytes(int64(len(dest.Pix)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		convertRGBA(dest, src)
+	}
+}
+
+func BenchmarkConvertGeneric(b *testing.B) {
+	dest, src := newBenchImage(), genericImage{newBenchRGBA()}
+	b.SetBytes(int64(len(dest.Pix)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		convertImage(dest, src)
+	}
+}
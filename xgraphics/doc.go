@@ -20,6 +20,12 @@ responding to expose events to redraw the pixmap.) The former approach requires
 less book-keeping, but supposedly has some issues with some video cards. The
 latter approach is probably more reliable, but requires more book-keeping.
 
+Images created with NewShmImage keep their pixel data in a shared memory
+segment that is attached to the X server with the MIT-SHM extension. Drawing
+them with XDraw doesn't send any pixel data over the X connection, which is
+much faster for large images. (If the extension or shared memory isn't
+available, NewShmImage returns a regular image instead.)
+
 Note that while text drawing functions are provided, it is not necessary to use
 them to write text on images. Namely, there is nothing X specific about them.
 They are strictly for convenience.
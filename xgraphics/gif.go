@@ -0,0 +1,182 @@
+package xgraphics
+
+/*
+xgraphics/gif.go contains support for decoding animated GIFs into a list of
+xgraphics.Image values, one for each frame, and for playing them in a window.
+*/
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+)
+
+// defaultGIFDelay is the delay used for frames that don't specify one.
+// (This is what most browsers do.)
+const defaultGIFDelay = 100 * time.Millisecond
+
+// AnimatedImage is a decoded animated GIF. Each frame is a complete image the
+// size of the whole animation, with the disposal method of every previous
+// frame already applied.
+type AnimatedImage struct {
+	// Frames holds one image for each frame of the animation.
+	Frames []*Image
+
+	// Delays holds how long each frame should be shown for.
+	Delays []time.Duration
+
+	// LoopCount is the loop count of the GIF. 0 means the animation loops
+	// forever, -1 means it is shown once, and any other value means it is
+	// shown LoopCount+1 times.
+	LoopCount int
+
+	// Current is the index of the frame currently being shown.
+	// While the animation is being played, use Frame instead of reading
+	// Current directly.
+	Current int
+
+	// currentLck protects Current, which is changed by Play.
+	currentLck sync.Mutex
+}
+
+// NewGIF decodes every frame of the GIF in 'r'. Frames are composited on to
+// each other the way a GIF viewer would show them. In particular, the
+// "restore to background" disposal method clears the area of a frame to
+// transparent, and the "restore to previous" disposal method restores the area
+// of a frame to what it was before the frame was drawn.
+// An error is returned if the GIF has no frames.
+func NewGIF(X *xgbutil.XUtil, r io.Reader) (*AnimatedImage, error) {
+	g, err := gif.DecodeAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(g.Image) == 0 {
+		return nil, fmt.Errorf("NewGIF: The GIF has no frames.")
+	}
+
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		bounds = g.Image[0].Bounds()
+	}
+	canvas := image.NewRGBA(bounds)
+	var previous *image.RGBA
+
+	anim := &AnimatedImage{
+		Frames:    make([]*Image, len(g.Image)),
+		Delays:    make([]time.Duration, len(g.Image)),
+		LoopCount: g.LoopCount,
+	}
+	for i, frame := range g.Image {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			copy(previous.Pix, canvas.Pix)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		anim.Frames[i] = NewConvert(X, canvas)
+
+		anim.Delays[i] = defaultGIFDelay
+		if i < len(g.Delay) && g.Delay[i] > 0 {
+			anim.Delays[i] = time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent,
+				image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			draw.Draw(canvas, frame.Bounds(), previous,
+				frame.Bounds().Min, draw.Src)
+		}
+	}
+	return anim, nil
+}
+
+// Frame returns the image of the current frame.
+func (anim *AnimatedImage) Frame() *Image {
+	anim.currentLck.Lock()
+	defer anim.currentLck.Unlock()
+
+	return anim.Frames[anim.Current]
+}
+
+// Next advances to the next frame, wrapping around to the first one after the
+// last. The image of the new current frame is returned.
+func (anim *AnimatedImage) Next() *Image {
+	anim.currentLck.Lock()
+	defer anim.currentLck.Unlock()
+
+	anim.Current = (anim.Current + 1) % len(anim.Frames)
+	return anim.Frames[anim.Current]
+}
+
+// currentSet changes the current frame.
+func (anim *AnimatedImage) currentSet(i int) {
+	anim.currentLck.Lock()
+	defer anim.currentLck.Unlock()
+
+	anim.Current = i
+}
+
+// Play paints the frames of the animation to 'wid' in a new goroutine,
+// showing each frame for its delay, until the loop count is exhausted or
+// 'stop' is closed. Each frame's pixmap is created, set as the surface of
+// 'wid' and drawn when it is first shown. After that, it is only made the
+// background of 'wid' again and painted.
+// Play must not be called again until the previous animation has stopped.
+func (anim *AnimatedImage) Play(wid xproto.Window, stop <-chan struct{}) {
+	if len(anim.Frames) == 0 {
+		return
+	}
+	go func() {
+		drawn := make([]bool, len(anim.Frames))
+		for loop := 0; anim.LoopCount <= 0 || loop <= anim.LoopCount; loop++ {
+			for i, frame := range anim.Frames {
+				anim.currentSet(i)
+				if !drawn[i] {
+					if err := frame.XSurfaceSet(wid); err != nil {
+						xgbutil.Logger.Printf("Play: Could not show frame "+
+							"%d: %s", i, err)
+						return
+					}
+					frame.XDraw()
+					drawn[i] = true
+				} else {
+					// The pixmap of the frame is ready, so only the
+					// background of the window needs to be switched to it.
+					xproto.ChangeWindowAttributes(frame.X.Conn(), wid,
+						xproto.CwBackPixmap, []uint32{uint32(frame.Pixmap)})
+				}
+				frame.XPaint(wid)
+
+				select {
+				case <-stop:
+					return
+				case <-time.After(anim.Delays[i]):
+				}
+			}
+			if anim.LoopCount < 0 {
+				return
+			}
+		}
+	}()
+}
+
+// Destroy frees the pixmaps of every frame.
+func (anim *AnimatedImage) Destroy() {
+	for _, frame := range anim.Frames {
+		frame.Destroy()
+	}
+}
@@ -0,0 +1,85 @@
+package xgraphics
+
+/*
+xgraphics/gradient.go contains methods for filling an image with linear and
+radial color gradients.
+*/
+
+import (
+	"image/color"
+	"math"
+)
+
+// GradientLinear fills the image with a linear gradient from 'start' to
+// 'end'. 'angle' is the direction of the gradient in degrees, where 0 goes
+// from left to right and 90 goes from top to bottom.
+// To fill only part of an image, call GradientLinear on a sub-image. (See
+// SubImage.)
+func (im *Image) GradientLinear(start, end color.Color, angle float64) {
+	r := im.Rect
+	if r.Empty() {
+		return
+	}
+	dx, dy := math.Cos(angle*math.Pi/180), math.Sin(angle*math.Pi/180)
+
+	// The gradient starts and ends at the corners of the image that are
+	// furthest along the gradient's direction.
+	corners := [4][2]float64{
+		{float64(r.Min.X), float64(r.Min.Y)},
+		{float64(r.Max.X - 1), float64(r.Min.Y)},
+		{float64(r.Min.X), float64(r.Max.Y - 1)},
+		{float64(r.Max.X - 1), float64(r.Max.Y - 1)},
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, c := range corners {
+		p := c[0]*dx + c[1]*dy
+		lo, hi = math.Min(lo, p), math.Max(hi, p)
+	}
+
+	c1, c2 := BGRAModel.Convert(start).(BGRA), BGRAModel.Convert(end).(BGRA)
+	im.For(func(x, y int) BGRA {
+		if hi == lo {
+			return c1
+		}
+		p := float64(x)*dx + float64(y)*dy
+		return gradientColor(c1, c2, (p-lo)/(hi-lo))
+	})
+}
+
+// GradientRadial fills the image with a radial gradient from 'inner' at the
+// center of the image to 'outer' at its corners.
+// To fill only part of an image, call GradientRadial on a sub-image. (See
+// SubImage.)
+func (im *Image) GradientRadial(inner, outer color.Color) {
+	r := im.Rect
+	if r.Empty() {
+		return
+	}
+	cx := float64(r.Min.X) + float64(r.Dx()-1)/2
+	cy := float64(r.Min.Y) + float64(r.Dy()-1)/2
+	radius := math.Hypot(float64(r.Dx()-1)/2, float64(r.Dy()-1)/2)
+
+	c1, c2 := BGRAModel.Convert(inner).(BGRA), BGRAModel.Convert(outer).(BGRA)
+	im.For(func(x, y int) BGRA {
+		if radius == 0 {
+			return c1
+		}
+		d := math.Hypot(float64(x)-cx, float64(y)-cy)
+		return gradientColor(c1, c2, d/radius)
+	})
+}
+
+// gradientColor returns the color at position 't' in [0, 1] of a gradient
+// from 'c1' to 'c2'.
+func gradientColor(c1, c2 BGRA, t float64) BGRA {
+	t = math.Max(0, math.Min(1, t))
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*t + 0.5)
+	}
+	return BGRA{
+		B: mix(c1.B, c2.B),
+		G: mix(c1.G, c2.G),
+		R: mix(c1.R, c2.R),
+		A: mix(c1.A, c2.A),
+	}
+}
@@ -25,6 +25,7 @@ seems like I'm doing something stupid, I probably am.
 import (
 	"image"
 	"image/color"
+	"image/jpeg"
 	"image/png"
 	"io"
 	"os"
@@ -64,6 +65,10 @@ type Image struct {
 	// Namely, sub-images cannot be set as surfaces and sub-images, when
 	// being drawn, only have its pixels sent to X instead of the whole image.
 	Subimg bool
+
+	// The shared memory segment holding Pix, if the image was created with
+	// NewShmImage and MIT-SHM could be used. Otherwise nil.
+	shm *shmSegment
 }
 
 // New returns a new instance of Image with colors initialized to black
@@ -98,6 +103,9 @@ func New(X *xgbutil.XUtil, r image.Rectangle) *Image {
 // Destroy frees the pixmap resource being used by this image.
 // It should be called whenever the image will no longer be drawn or painted.
 func (im *Image) Destroy() {
+	if im.shm != nil && !im.Subimg {
+		im.shmDestroy()
+	}
 	if im.Pixmap != 0 {
 		xproto.FreePixmap(im.X.Conn(), im.Pixmap)
 		im.Pixmap = 0
@@ -110,14 +118,85 @@ func (im *Image) Destroy() {
 // this image is painted to. (And obviously, XDraw and XPaint will need to
 // be called again.)
 func (im *Image) Scale(width, height int) *Image {
+	return im.ScaleExtra(width, height, ScaleBilinear)
+}
+
+// ScaleMode is the interpolation used when scaling an image.
+type ScaleMode int
+
+const (
+	// ScaleBilinear is slower but produces smoother results.
+	ScaleBilinear ScaleMode = iota
+
+	// ScaleNearest is fast, but produces blocky results.
+	ScaleNearest
+)
+
+// ScaleExtra is the same as Scale, but the interpolation used can be chosen.
+// A width or height smaller than 1 is treated as 1.
+func (im *Image) ScaleExtra(width, height int, mode ScaleMode) *Image {
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+
 	dimg := New(im.X, image.Rect(0, 0, width, height))
-	graphics.Scale(dimg, im)
+	switch mode {
+	case ScaleNearest:
+		scaleNearest(dimg, im)
+	default:
+		graphics.Scale(dimg, im)
+	}
 	im.Destroy()
 
 	return dimg
 }
 
+// scaleNearest scales 'src' to the size of 'dst' using nearest neighbor
+// interpolation.
+func scaleNearest(dst, src *Image) {
+	sb, db := src.Bounds(), dst.Bounds()
+	if sb.Empty() {
+		return
+	}
+	for y := db.Min.Y; y < db.Max.Y; y++ {
+		sy := sb.Min.Y + (y-db.Min.Y)*sb.Dy()/db.Dy()
+		for x := db.Min.X; x < db.Max.X; x++ {
+			sx := sb.Min.X + (x-db.Min.X)*sb.Dx()/db.Dx()
+			si, di := src.PixOffset(sx, sy), dst.PixOffset(x, y)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+}
+
+// AspectFit returns the largest size with the same aspect ratio as
+// (width, height) that fits inside (maxWidth, maxHeight). This is useful for
+// computing the size to pass to Scale when creating thumbnails.
+// Neither dimension returned is ever smaller than 1.
+func AspectFit(width, height, maxWidth, maxHeight int) (int, int) {
+	w, h := maxWidth, maxHeight
+	if width > 0 && height > 0 {
+		// Compare width/height with maxWidth/maxHeight without dividing.
+		if width*maxHeight > height*maxWidth {
+			h = height * maxWidth / width
+		} else {
+			w = width * maxHeight / height
+		}
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return w, h
+}
+
 // WritePng encodes the image to w as a png.
+// The alpha channel is kept, so an image loaded from a png file with
+// transparency is written back the same way.
 func (im *Image) WritePng(w io.Writer) error {
 	return png.Encode(w, im)
 }
@@ -128,7 +207,32 @@ func (im *Image) SavePng(name string) error {
 	if err != nil {
 		return err
 	}
-	return im.WritePng(file)
+	if err := im.WritePng(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WriteJpeg encodes the image to w as a jpeg. If 'options' is nil, the default
+// quality is used.
+// Since jpeg images have no alpha channel, transparent parts of the image are
+// drawn over black.
+func (im *Image) WriteJpeg(w io.Writer, options *jpeg.Options) error {
+	return jpeg.Encode(w, im, options)
+}
+
+// SaveJpeg writes the Image to a file with name as a jpeg.
+func (im *Image) SaveJpeg(name string, options *jpeg.Options) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := im.WriteJpeg(file, options); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
 }
 
 // ColorModel returns the color.Model used by the Image struct.
@@ -235,6 +339,7 @@ func (im *Image) SubImage(r image.Rectangle) image.Image {
 		Stride: im.Stride,
 		Rect:   r,
 		Subimg: true,
+		shm:    im.shm,
 	}
 }
 
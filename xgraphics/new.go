@@ -87,6 +87,8 @@ func NewBytes(X *xgbutil.XUtil, bs []byte) (*Image, error) {
 }
 
 // NewEwmhIcon converts EWMH icon data (ARGB) to an xgraphics.Image type.
+// The colors in _NET_WM_ICON are not premultiplied by alpha, so they are
+// premultiplied here to match the BGRA color type.
 // You should probably use xgraphics.FindIcon instead of this directly.
 func NewEwmhIcon(X *xgbutil.XUtil, icon *ewmh.WmIcon) *Image {
 	ximg := New(X, image.Rect(0, 0, int(icon.Width), int(icon.Height)))
@@ -97,11 +99,12 @@ func NewEwmhIcon(X *xgbutil.XUtil, icon *ewmh.WmIcon) *Image {
 	for x = r.Min.X; x < r.Max.X; x++ {
 		for y = r.Min.Y; y < r.Max.Y; y++ {
 			argb = int(icon.Data[x+(y*width)])
+			a := argb >> 24
 			ximg.SetBGRA(x, y, BGRA{
-				B: uint8(argb & 0x000000ff),
-				G: uint8((argb & 0x0000ff00) >> 8),
-				R: uint8((argb & 0x00ff0000) >> 16),
-				A: uint8(argb >> 24),
+				B: uint8((argb & 0x000000ff) * a / 0xff),
+				G: uint8(((argb & 0x0000ff00) >> 8) * a / 0xff),
+				R: uint8(((argb & 0x00ff0000) >> 16) * a / 0xff),
+				A: uint8(a),
 			})
 		}
 	}
@@ -171,7 +174,12 @@ func NewIcccmIcon(X *xgbutil.XUtil, iconPixmap,
 }
 
 // NewDrawable converts an X drawable into a xgraphics.Image.
-// This is used in NewIcccmIcon.
+// This is used in NewIcccmIcon, and can also be used to take a screenshot of
+// a window. (Or of the whole screen, using the root window.)
+// Windows must be viewable, and only the parts of a window that are on the
+// screen and not covered by other windows are guaranteed to be captured.
+// (Unless a compositing manager is running.) An error is returned if the
+// window isn't viewable or is partially off the screen.
 func NewDrawable(X *xgbutil.XUtil, did xproto.Drawable) (*Image, error) {
 	// Get the geometry of the pixmap for use in the GetImage request.
 	pgeom, err := xwindow.RawGeometry(X, xproto.Drawable(did))
@@ -185,6 +193,11 @@ func NewDrawable(X *xgbutil.XUtil, did xproto.Drawable) (*Image, error) {
 		0, 0, uint16(pgeom.Width()), uint16(pgeom.Height()),
 		(1<<32)-1).Reply()
 	if err != nil {
+		if _, ok := err.(xproto.MatchError); ok {
+			return nil, fmt.Errorf("NewDrawable: Could not get the contents "+
+				"of drawable %x, which is probably an unmapped window or a "+
+				"window partially off the screen: %s", did, err)
+		}
 		return nil, err
 	}
 
@@ -249,29 +262,43 @@ func readDrawableData(X *xgbutil.XUtil, ximg *Image, did xproto.Drawable,
 		}
 	case 24, 32:
 		switch format.BitsPerPixel {
-		case 24:
+		case 24, 32:
+			// Scanlines are padded to ScanlinePad bits.
 			bytesPer := int(format.BitsPerPixel) / 8
+			pad := int(format.ScanlinePad) / 8
+			if pad == 0 {
+				pad = 1
+			}
+			stride := (width*bytesPer + pad - 1) / pad * pad
+			if len(imgData.Data) < stride*height {
+				return fmt.Errorf("The image returned for pixmap id %d is "+
+					"too short: expected %d bytes but got %d.",
+					did, stride*height, len(imgData.Data))
+			}
+
+			// With a most significant byte first server, the bytes of each
+			// pixel are in the reverse order.
+			b, g, r, a := 0, 1, 2, 3
+			if X.Setup().ImageByteOrder == xproto.ImageOrderMSBFirst {
+				b, g, r, a = bytesPer-1, bytesPer-2, bytesPer-3, 0
+			}
+
+			// Only 32 bit deep drawables have an alpha channel. Otherwise,
+			// the fourth byte is just padding.
+			hasAlpha := format.Depth == 32 && bytesPer == 4
 			var i int
 			ximg.For(func(x, y int) BGRA {
-				i = y*width*bytesPer + x*bytesPer
-				return BGRA{
-					B: imgData.Data[i],
-					G: imgData.Data[i+1],
-					R: imgData.Data[i+2],
+				i = y*stride + x*bytesPer
+				c := BGRA{
+					B: imgData.Data[i+b],
+					G: imgData.Data[i+g],
+					R: imgData.Data[i+r],
 					A: 0xff,
 				}
-			})
-		case 32:
-			bytesPer := int(format.BitsPerPixel) / 8
-			var i int
-			ximg.For(func(x, y int) BGRA {
-				i = y*width*bytesPer + x*bytesPer
-				return BGRA{
-					B: imgData.Data[i],
-					G: imgData.Data[i+1],
-					R: imgData.Data[i+2],
-					A: imgData.Data[i+3],
+				if hasAlpha {
+					c.A = imgData.Data[i+a]
 				}
+				return c
 			})
 		default:
 			return fmt.Errorf("The image returned for pixmap id %d has "+
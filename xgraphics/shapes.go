@@ -0,0 +1,124 @@
+package xgraphics
+
+/*
+xgraphics/shapes.go contains methods for drawing simple anti-aliased shapes,
+like rounded rectangles and circles, directly on to an image.
+*/
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// DrawRoundRect fills the rectangle 'r' with the color 'fill', using corners
+// rounded with the given radius. A radius of 0 draws a plain rectangle, and
+// the radius is limited to half of the smaller side of 'r'.
+// The edges of the corners are anti-aliased, and 'fill' is drawn over the
+// pixels already in the image.
+func (im *Image) DrawRoundRect(r image.Rectangle, radius int,
+	fill color.Color) {
+
+	im.drawShape(r, fill, roundRectDist(r, radius))
+}
+
+// StrokeRoundRect is just like DrawRoundRect, except only the outline of the
+// rounded rectangle is drawn, with the given line width. The outline is
+// drawn inside 'r'.
+func (im *Image) StrokeRoundRect(r image.Rectangle, radius, width int,
+	stroke color.Color) {
+
+	im.drawShape(r, stroke, strokeDist(roundRectDist(r, radius), width))
+}
+
+// DrawCircle fills a circle with the color 'fill', centered on the pixel at
+// (cx, cy) and with the given radius.
+// The edge of the circle is anti-aliased, and 'fill' is drawn over the pixels
+// already in the image.
+func (im *Image) DrawCircle(cx, cy, radius int, fill color.Color) {
+	im.drawShape(circleBounds(cx, cy, radius), fill,
+		circleDist(cx, cy, radius))
+}
+
+// StrokeCircle is just like DrawCircle, except only the outline of the circle
+// is drawn, with the given line width. The outline is drawn inside the
+// circle.
+func (im *Image) StrokeCircle(cx, cy, radius, width int, stroke color.Color) {
+	im.drawShape(circleBounds(cx, cy, radius), stroke,
+		strokeDist(circleDist(cx, cy, radius), width))
+}
+
+// shapeDist is the signed distance from the point (x, y) to the edge of a
+// shape. It is negative inside the shape and positive outside of it.
+type shapeDist func(x, y float64) float64
+
+// drawShape draws the color 'c' over every pixel in 'bounds', weighted by how
+// much of the pixel is covered by the shape described by 'dist'.
+func (im *Image) drawShape(bounds image.Rectangle, c color.Color,
+	dist shapeDist) {
+
+	src := BGRAModel.Convert(c).(BGRA)
+	bounds = bounds.Intersect(im.Rect)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			// The coverage of a pixel is approximated by the distance from its
+			// center to the edge of the shape.
+			coverage := 0.5 - dist(float64(x)+0.5, float64(y)+0.5)
+			if coverage <= 0 {
+				continue
+			}
+			if coverage > 1 {
+				coverage = 1
+			}
+
+			i := im.PixOffset(x, y)
+			inv := 1 - float64(src.A)*coverage/0xff
+			for j, s := range []uint8{src.B, src.G, src.R, src.A} {
+				d := float64(im.Pix[i+j])
+				im.Pix[i+j] = uint8(float64(s)*coverage + d*inv + 0.5)
+			}
+		}
+	}
+}
+
+// roundRectDist returns the distance function of a rectangle with rounded
+// corners.
+func roundRectDist(r image.Rectangle, radius int) shapeDist {
+	r = r.Canon()
+	cx := float64(r.Min.X+r.Max.X) / 2
+	cy := float64(r.Min.Y+r.Max.Y) / 2
+	hw, hh := float64(r.Dx())/2, float64(r.Dy())/2
+	rad := math.Max(0, math.Min(float64(radius), math.Min(hw, hh)))
+
+	return func(x, y float64) float64 {
+		qx := math.Abs(x-cx) - (hw - rad)
+		qy := math.Abs(y-cy) - (hh - rad)
+		outside := math.Hypot(math.Max(qx, 0), math.Max(qy, 0))
+		inside := math.Min(math.Max(qx, qy), 0)
+		return outside + inside - rad
+	}
+}
+
+// circleDist returns the distance function of a circle centered on the pixel
+// at (cx, cy).
+func circleDist(cx, cy, radius int) shapeDist {
+	fx, fy := float64(cx)+0.5, float64(cy)+0.5
+	return func(x, y float64) float64 {
+		return math.Hypot(x-fx, y-fy) - float64(radius)
+	}
+}
+
+// circleBounds returns the pixels that a circle can cover.
+func circleBounds(cx, cy, radius int) image.Rectangle {
+	return image.Rect(cx-radius, cy-radius, cx+radius+1, cy+radius+1)
+}
+
+// strokeDist turns the distance function of a shape into the distance
+// function of its outline with the given width, drawn inside the shape.
+func strokeDist(dist shapeDist, width int) shapeDist {
+	w := float64(width)
+	return func(x, y float64) float64 {
+		d := dist(x, y)
+		return math.Max(d, -d-w)
+	}
+}
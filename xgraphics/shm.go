@@ -0,0 +1,125 @@
+package xgraphics
+
+/*
+xgraphics/shm.go contains support for images whose pixel data lives in a
+shared memory segment that is attached to the X server with the MIT-SHM
+extension. Drawing such an image only sends a small ShmPutImage request
+instead of all of its pixel data.
+*/
+
+import (
+	"fmt"
+	"image"
+	"runtime"
+
+	"github.com/jezek/xgb/shm"
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+)
+
+// shmSegment describes the shared memory segment holding the pixel data of
+// an image and all of its sub-images.
+// The segment is detached from the X server when the image is destroyed, but
+// its memory is only unmapped once no image uses the segment, since
+// sub-images may still refer to it.
+type shmSegment struct {
+	seg      shm.Seg
+	mapping  []byte
+	rect     image.Rectangle
+	attached bool
+}
+
+// NewShmImage is just like New, except the pixel data of the image is put in
+// a shared memory segment when possible. XDraw then uses a ShmPutImage
+// request, which is much faster than PutImage for large images.
+// If the MIT-SHM extension isn't available, or shared memory can't be used
+// (i.e., when connected to a remote X server), NewShmImage falls back to
+// returning an image created by New.
+//
+// The X server reads the pixel data some time after XDraw returns. If the
+// image is modified right after drawing, call X.Sync first.
+// Destroy must be called to release the shared memory segment.
+func NewShmImage(X *xgbutil.XUtil, r image.Rectangle) *Image {
+	im := New(X, r)
+	if err := im.shmAttach(); err != nil {
+		xgbutil.Logger.Printf("NewShmImage: Using PutImage instead of "+
+			"ShmPutImage because: %s", err)
+	}
+	return im
+}
+
+// shmAttach moves the pixel data of the image into a new shared memory
+// segment and attaches it to the X server.
+func (im *Image) shmAttach() error {
+	if !im.X.ExtInitialized("MIT-SHM") {
+		if err := shm.Init(im.X.Conn()); err != nil {
+			return fmt.Errorf("MIT-SHM extension is not available: %s", err)
+		}
+	}
+
+	id, mapping, mem, err := shmCreate(len(im.Pix))
+	if err != nil {
+		return err
+	}
+
+	// The segment is marked for removal as soon as the X server has attached
+	// it (or failed to), so that it doesn't outlive this process.
+	defer shmRemove(id)
+
+	seg, err := shm.NewSegId(im.X.Conn())
+	if err != nil {
+		shmDetachMem(mapping)
+		return err
+	}
+	err = shm.AttachChecked(im.X.Conn(), seg, uint32(id), true).Check()
+	if err != nil {
+		shmDetachMem(mapping)
+		return fmt.Errorf("could not attach shared memory segment: %s", err)
+	}
+
+	copy(mem, im.Pix)
+	im.Pix = mem
+	im.shm = &shmSegment{
+		seg:      seg,
+		mapping:  mapping,
+		rect:     im.Rect,
+		attached: true,
+	}
+	runtime.SetFinalizer(im.shm, func(s *shmSegment) {
+		shmDetachMem(s.mapping)
+	})
+	return nil
+}
+
+// shmDestroy detaches the shared memory segment of the image from the X
+// server. Sub-images of the image fall back to PutImage afterwards, and the
+// memory is unmapped once they are all gone.
+func (im *Image) shmDestroy() {
+	shm.Detach(im.X.Conn(), im.shm.seg)
+	im.shm.attached = false
+	im.Pix = nil
+	im.shm = nil
+}
+
+// xdrawShm is the ShmPutImage version of xdraw. Sub-images use the segment
+// of their parent image, so no pixel data needs to be copied.
+func (im *Image) xdrawShm(checked bool) error {
+	full := im.shm.rect
+	srcX, srcY := im.Rect.Min.X-full.Min.X, im.Rect.Min.Y-full.Min.Y
+
+	cookie := shm.PutImage
+	if checked {
+		cookie = shm.PutImageChecked
+	}
+	c := cookie(im.X.Conn(), xproto.Drawable(im.Pixmap), im.X.GC(),
+		uint16(full.Dx()), uint16(full.Dy()),
+		uint16(srcX), uint16(srcY),
+		uint16(im.Rect.Dx()), uint16(im.Rect.Dy()),
+		int16(im.Rect.Min.X), int16(im.Rect.Min.Y),
+		24, xproto.ImageFormatZPixmap, 0, im.shm.seg, 0)
+	if checked {
+		return c.Check()
+	}
+	return nil
+}
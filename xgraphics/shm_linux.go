@@ -0,0 +1,71 @@
+//go:build linux && (amd64 || arm || arm64 || loong64 || mips64 || mips64le || riscv64)
+
+package xgraphics
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// System V IPC constants from <sys/ipc.h> and <sys/shm.h>.
+const (
+	ipcPrivate = 0
+	ipcCreat   = 01000
+	ipcRmid    = 0
+	shmRemap   = 040000
+)
+
+// shmAlign is the alignment used for the address that a shared memory segment
+// is attached at. It is at least SHMLBA on every supported architecture.
+const shmAlign = 1 << 18
+
+// shmCreate allocates a new shared memory segment of 'size' bytes and maps it
+// into the address space of this process. It returns the id of the segment,
+// the mapping that must be passed to shmDetachMem and the memory of the
+// segment itself (which is part of the mapping).
+//
+// The address range is reserved with mmap first, and the segment is then
+// attached on top of it. This way, the memory is only ever reached through
+// the slice returned by syscall.Mmap.
+func shmCreate(size int) (int, []byte, []byte, error) {
+	id, _, errno := syscall.Syscall(syscall.SYS_SHMGET,
+		ipcPrivate, uintptr(size), ipcCreat|0600)
+	if errno != 0 {
+		return 0, nil, nil, fmt.Errorf("shmget failed: %s", errno)
+	}
+
+	mapping, err := syscall.Mmap(-1, 0, size+shmAlign,
+		syscall.PROT_READ|syscall.PROT_WRITE,
+		syscall.MAP_PRIVATE|syscall.MAP_ANONYMOUS)
+	if err != nil {
+		shmRemove(int(id))
+		return 0, nil, nil, fmt.Errorf("mmap failed: %s", err)
+	}
+	off := 0
+	if rem := int(uintptr(unsafe.Pointer(&mapping[0])) % shmAlign); rem > 0 {
+		off = shmAlign - rem
+	}
+	mem := mapping[off : off+size]
+
+	_, _, errno = syscall.Syscall(syscall.SYS_SHMAT, id,
+		uintptr(unsafe.Pointer(&mem[0])), shmRemap)
+	if errno != 0 {
+		syscall.Munmap(mapping)
+		shmRemove(int(id))
+		return 0, nil, nil, fmt.Errorf("shmat failed: %s", errno)
+	}
+	return int(id), mapping, mem, nil
+}
+
+// shmRemove marks a shared memory segment to be destroyed once it is no
+// longer attached to any process.
+func shmRemove(id int) {
+	syscall.Syscall(syscall.SYS_SHMCTL, uintptr(id), ipcRmid, 0)
+}
+
+// shmDetachMem unmaps a mapping returned by shmCreate, which also detaches
+// the shared memory segment in it.
+func shmDetachMem(mapping []byte) {
+	syscall.Munmap(mapping)
+}
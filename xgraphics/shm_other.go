@@ -0,0 +1,18 @@
+//go:build !linux || !(amd64 || arm || arm64 || loong64 || mips64 || mips64le || riscv64)
+
+package xgraphics
+
+import (
+	"fmt"
+)
+
+// shmCreate always fails, since shared memory isn't supported on this
+// platform yet.
+func shmCreate(size int) (int, []byte, []byte, error) {
+	return 0, nil, nil, fmt.Errorf("shared memory is not supported on this " +
+		"platform")
+}
+
+func shmRemove(id int) {}
+
+func shmDetachMem(mapping []byte) {}
@@ -5,6 +5,7 @@ import (
 	"image/color"
 	"io"
 	"io/ioutil"
+	"unicode/utf8"
 
 	"github.com/BurntSushi/freetype-go/freetype"
 	"github.com/BurntSushi/freetype-go/freetype/truetype"
@@ -55,7 +56,8 @@ func Extents(font *truetype.Font, fontSize float64, text string) (int, int) {
 
 // Returns the max width and height extents of a string given a font.
 // This is calculated by determining the number of pixels in an "em" unit
-// for the given font, and multiplying by the number of characters in 'text'.
+// for the given font, and multiplying by the number of characters (not bytes)
+// in 'text'.
 // Since a particular character may be smaller than one "em" unit, this has
 // a tendency to overestimate the extents.
 // It is provided because I do not know how to calculate the precise extents
@@ -66,7 +68,7 @@ func TextMaxExtents(font *truetype.Font, fontSize float64,
 
 	c := ftContext(font, fontSize)
 	emSquarePix := int(c.PointToFix32(fontSize) >> 8)
-	return len(text) * emSquarePix, emSquarePix
+	return utf8.RuneCountInString(text) * emSquarePix, emSquarePix
 }
 
 // ftContext does the boiler plate to create a freetype context
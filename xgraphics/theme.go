@@ -0,0 +1,275 @@
+package xgraphics
+
+/*
+xgraphics/theme.go contains support for finding icons by name in icon themes,
+as described by the freedesktop.org Icon Theme Specification.
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jezek/xgbutil"
+)
+
+// IconTheme is the name of the icon theme searched first by FindThemeIcon.
+// The themes it inherits from, and finally the "hicolor" theme, are searched
+// after it. If it's empty, only "hicolor" is searched.
+var IconTheme = ""
+
+// iconExtensions are the file extensions of the icons that FindThemeIcon can
+// decode, in order of preference. (SVG icons are not supported.)
+var iconExtensions = []string{".png", ".xpm"}
+
+// iconThemeDir describes a directory of icons in an icon theme.
+type iconThemeDir struct {
+	path                           string
+	typ                            string
+	size, minSize, maxSize, thresh int
+}
+
+// matches reports whether icons in the directory can be used at 'size'
+// without scaling.
+func (d iconThemeDir) matches(size int) bool {
+	switch d.typ {
+	case "Fixed":
+		return d.size == size
+	case "Scalable":
+		return d.minSize <= size && size <= d.maxSize
+	}
+	return d.size-d.thresh <= size && size <= d.size+d.thresh
+}
+
+// distance is how far icons in the directory are from 'size'.
+func (d iconThemeDir) distance(size int) int {
+	switch d.typ {
+	case "Fixed":
+		return abs(d.size - size)
+	case "Scalable":
+		if size < d.minSize {
+			return d.minSize - size
+		}
+		if size > d.maxSize {
+			return size - d.maxSize
+		}
+		return 0
+	}
+	if size < d.size-d.thresh {
+		return d.size - d.thresh - size
+	}
+	if size > d.size+d.thresh {
+		return size - d.size - d.thresh
+	}
+	return 0
+}
+
+// FindThemeIcon finds the icon called 'name' (without an extension, like
+// "firefox" or "document-open") that is closest to 'size' pixels, and
+// decodes it into an xgraphics.Image.
+// IconTheme, the themes it inherits from and "hicolor" are searched in that
+// order, in all of the standard icon directories ($HOME/.icons,
+// $XDG_DATA_HOME/icons and the icons directory of each directory in
+// $XDG_DATA_DIRS). If no theme has the icon, /usr/share/pixmaps is tried.
+// Only PNG and XPM icons are supported. The icon is not scaled, so it may
+// be smaller or larger than 'size'. (See Scale.)
+// An error is returned if the icon can't be found or decoded.
+func FindThemeIcon(X *xgbutil.XUtil, name string, size int) (*Image, error) {
+	bases := iconBaseDirs()
+
+	themes := []string{}
+	if len(IconTheme) > 0 {
+		themes = iconThemeChain(bases, IconTheme, themes)
+	}
+	themes = iconThemeChain(bases, "hicolor", themes)
+
+	for _, theme := range themes {
+		if fileName := findThemeIcon(bases, theme, name, size); fileName != "" {
+			return NewFileName(X, fileName)
+		}
+	}
+
+	for _, base := range append(bases, "/usr/share/pixmaps") {
+		if fileName := findIconFile(base, name); fileName != "" {
+			return NewFileName(X, fileName)
+		}
+	}
+	return nil, fmt.Errorf("FindThemeIcon: Could not find an icon named %q.",
+		name)
+}
+
+// iconBaseDirs returns the directories that contain icon themes, in order
+// of preference.
+func iconBaseDirs() []string {
+	var dirs []string
+	home := os.Getenv("HOME")
+	if len(home) > 0 {
+		dirs = append(dirs, filepath.Join(home, ".icons"))
+	}
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if len(dataHome) == 0 && len(home) > 0 {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	if len(dataHome) > 0 {
+		dirs = append(dirs, filepath.Join(dataHome, "icons"))
+	}
+
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if len(dataDirs) == 0 {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range filepath.SplitList(dataDirs) {
+		if len(dir) > 0 {
+			dirs = append(dirs, filepath.Join(dir, "icons"))
+		}
+	}
+	return dirs
+}
+
+// iconThemeChain appends 'theme' and every theme it inherits from (depth
+// first) to 'themes', skipping themes that are already in it.
+func iconThemeChain(bases []string, theme string, themes []string) []string {
+	for _, t := range themes {
+		if t == theme {
+			return themes
+		}
+	}
+	themes = append(themes, theme)
+
+	inherits, _ := readIconTheme(bases, theme)
+	for _, parent := range inherits {
+		themes = iconThemeChain(bases, parent, themes)
+	}
+	return themes
+}
+
+// findThemeIcon returns the file name of the icon in 'theme' that is the best
+// match for 'size', or an empty string if the theme doesn't have the icon.
+func findThemeIcon(bases []string, theme, name string, size int) string {
+	_, dirs := readIconTheme(bases, theme)
+
+	best, bestDist := "", -1
+	for _, dir := range dirs {
+		for _, base := range bases {
+			fileName := findIconFile(
+				filepath.Join(base, theme, dir.path), name)
+			if fileName == "" {
+				continue
+			}
+			if dir.matches(size) {
+				return fileName
+			}
+			if dist := dir.distance(size); bestDist < 0 || dist < bestDist {
+				best, bestDist = fileName, dist
+			}
+		}
+	}
+	return best
+}
+
+// findIconFile returns the file name of the icon called 'name' in 'dir',
+// or an empty string if there isn't one with a supported extension.
+func findIconFile(dir, name string) string {
+	for _, ext := range iconExtensions {
+		fileName := filepath.Join(dir, name+ext)
+		if info, err := os.Stat(fileName); err == nil && !info.IsDir() {
+			return fileName
+		}
+	}
+	return ""
+}
+
+// readIconTheme reads the index.theme file of 'theme' from the first base
+// directory that has one. It returns the themes that 'theme' inherits from
+// and the directories of unscaled icons in the theme.
+func readIconTheme(bases []string,
+	theme string) ([]string, []iconThemeDir) {
+
+	for _, base := range bases {
+		f, err := os.Open(filepath.Join(base, theme, "index.theme"))
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+
+		sections := make(map[string]map[string]string)
+		section := ""
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if len(line) == 0 || line[0] == '#' {
+				continue
+			}
+			if line[0] == '[' && line[len(line)-1] == ']' {
+				section = line[1 : len(line)-1]
+				sections[section] = make(map[string]string)
+				continue
+			}
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 || sections[section] == nil {
+				continue
+			}
+			key, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+			sections[section][key] = val
+		}
+
+		themeKeys := sections["Icon Theme"]
+		if themeKeys == nil {
+			return nil, nil
+		}
+		inherits := splitThemeList(themeKeys["Inherits"])
+		dirNames := splitThemeList(themeKeys["Directories"])
+
+		var dirs []iconThemeDir
+		for _, name := range dirNames {
+			keys := sections[name]
+			if keys == nil || themeInt(keys["Scale"], 1) != 1 {
+				continue
+			}
+			size := themeInt(keys["Size"], 0)
+			dirs = append(dirs, iconThemeDir{
+				path:    name,
+				typ:     keys["Type"],
+				size:    size,
+				minSize: themeInt(keys["MinSize"], size),
+				maxSize: themeInt(keys["MaxSize"], size),
+				thresh:  themeInt(keys["Threshold"], 2),
+			})
+		}
+		return inherits, dirs
+	}
+	return nil, nil
+}
+
+// splitThemeList splits a comma separated list from an index.theme file.
+func splitThemeList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// themeInt parses an integer from an index.theme file, returning 'def' if
+// it's missing or invalid.
+func themeInt(s string, def int) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
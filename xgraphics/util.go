@@ -50,6 +50,31 @@ func Alpha(dest *Image, alpha int) {
 // Blend does not (currently) blend with the destination's alpha channel,
 // only the source's alpha channel.
 func Blend(dest *Image, src image.Image, sp image.Point) {
+	BlendExtra(dest, src, sp, BlendOver)
+}
+
+// BlendMode determines how the colors of a source and destination pixel are
+// combined by BlendExtra, before the source's alpha channel is applied.
+type BlendMode int
+
+const (
+	// BlendOver uses the source color. (This is regular alpha blending.)
+	BlendOver BlendMode = iota
+
+	// BlendMultiply multiplies the colors, which always darkens.
+	BlendMultiply
+
+	// BlendScreen multiplies the inverted colors, which always lightens.
+	BlendScreen
+
+	// BlendAdd adds the colors, clamping to white.
+	BlendAdd
+)
+
+// BlendExtra is the same as Blend, except the colors of each pair of pixels
+// are combined using 'mode'. The result is then alpha blended into the
+// destination using the source's alpha channel.
+func BlendExtra(dest *Image, src image.Image, sp image.Point, mode BlendMode) {
 	rsrc, dsrc := src.Bounds(), dest.Bounds()
 	_, smxx, _, smxy := rsrc.Min.X, rsrc.Max.X, rsrc.Min.Y, rsrc.Max.Y
 	dmnx, dmxx, dmny, dmxy := dsrc.Min.X, dsrc.Max.X, dsrc.Min.Y, dsrc.Max.Y
@@ -65,15 +90,32 @@ func Blend(dest *Image, src image.Image, sp image.Point) {
 			alpha = float64(uint8(sa)) / 255.0
 
 			dest.SetBGRA(dx, dy, BGRA{
-				blend(uint8(bgra.B), uint8(sb), alpha),
-				blend(uint8(bgra.G), uint8(sg), alpha),
-				blend(uint8(bgra.R), uint8(sr), alpha),
+				blend(bgra.B, mode.mix(bgra.B, uint8(sb)), alpha),
+				blend(bgra.G, mode.mix(bgra.G, uint8(sg)), alpha),
+				blend(bgra.R, mode.mix(bgra.R, uint8(sr)), alpha),
 				0xff,
 			})
 		}
 	}
 }
 
+// mix combines a destination and source color component according to the
+// blend mode.
+func (mode BlendMode) mix(d, s uint8) uint8 {
+	switch mode {
+	case BlendMultiply:
+		return uint8(uint(d) * uint(s) / 0xff)
+	case BlendScreen:
+		return 0xff - uint8((0xff-uint(d))*(0xff-uint(s))/0xff)
+	case BlendAdd:
+		if uint(d)+uint(s) > 0xff {
+			return 0xff
+		}
+		return d + s
+	}
+	return s
+}
+
 // BlendBgColor blends the Image (receiver) into the background color
 // specified. This is more efficient than creating a background image and
 // blending with Blend.
@@ -162,6 +204,47 @@ func FindIcon(X *xgbutil.XUtil, wid xproto.Window,
 	return icon, nil
 }
 
+// WmIconImage returns the icon in _NET_WM_ICON of the window 'wid' whose
+// size is closest to 'size' pixels, converted to an xgraphics.Image. The
+// size of an icon is the larger of its width and height, and larger icons are
+// preferred when two are equally close. The icon is not scaled.
+// (This lives here rather than in the ewmh package, since xgraphics depends
+// on ewmh.) Use FindIcon to also fall back to the icon in WM_HINTS and to
+// scale the result.
+func WmIconImage(X *xgbutil.XUtil, wid xproto.Window,
+	size int) (*Image, error) {
+
+	icons, err := ewmh.WmIconGet(X, wid)
+	if err != nil {
+		return nil, err
+	}
+
+	best, bestDist := -1, 0
+	for i, icon := range icons {
+		if icon.Width == 0 || icon.Height == 0 {
+			continue
+		}
+		iconSize := int(icon.Width)
+		if int(icon.Height) > iconSize {
+			iconSize = int(icon.Height)
+		}
+		dist := iconSize - size
+		if dist < 0 {
+			dist = -dist
+		}
+		if best == -1 || dist < bestDist || (dist == bestDist &&
+			icon.Width*icon.Height > icons[best].Width*icons[best].Height) {
+
+			best, bestDist = i, dist
+		}
+	}
+	if best == -1 {
+		return nil, fmt.Errorf("WmIconImage: Window %x has no icons in "+
+			"_NET_WM_ICON.", wid)
+	}
+	return NewEwmhIcon(X, &icons[best]), nil
+}
+
 // findIconEwmh helps FindIcon by trying to return an ewmh-style icon that is
 // closest to the preferred size specified.
 func findIconEwmh(X *xgbutil.XUtil, wid xproto.Window,
@@ -0,0 +1,52 @@
+package xgraphics
+
+import (
+	"image"
+	"testing"
+)
+
+// newPixel returns a 1x1 image of the color provided. It isn't connected to
+// an X server.
+func newPixel(c BGRA) *Image {
+	return &Image{
+		Pix:    []uint8{c.B, c.G, c.R, c.A},
+		Stride: 4,
+		Rect:   image.Rect(0, 0, 1, 1),
+	}
+}
+
+func TestBlendExtra(t *testing.T) {
+	dst := BGRA{B: 100, G: 200, R: 50, A: 0xff}
+	tests := []struct {
+		name string
+		mode BlendMode
+		src  BGRA
+		want BGRA
+	}{
+		{"over", BlendOver,
+			BGRA{120, 40, 255, 0xff}, BGRA{120, 40, 255, 0xff}},
+		{"over half", BlendOver,
+			BGRA{120, 40, 255, 128}, BGRA{110, 119, 152, 0xff}},
+		{"over transparent", BlendOver,
+			BGRA{120, 40, 255, 0}, dst},
+		{"multiply", BlendMultiply,
+			BGRA{120, 40, 255, 0xff}, BGRA{47, 31, 50, 0xff}},
+		{"multiply transparent", BlendMultiply,
+			BGRA{120, 40, 255, 0}, dst},
+		{"screen", BlendScreen,
+			BGRA{120, 40, 255, 0xff}, BGRA{173, 209, 255, 0xff}},
+		{"screen transparent", BlendScreen,
+			BGRA{120, 40, 255, 0}, dst},
+		{"add", BlendAdd,
+			BGRA{120, 40, 255, 0xff}, BGRA{220, 240, 255, 0xff}},
+		{"add transparent", BlendAdd,
+			BGRA{120, 40, 255, 0}, dst},
+	}
+	for _, test := range tests {
+		img := newPixel(dst)
+		BlendExtra(img, newPixel(test.src), image.Point{}, test.mode)
+		if got := img.At(0, 0).(BGRA); got != test.want {
+			t.Errorf("%s: Expected %v, but got %v.", test.name, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package xgraphics
+
+/*
+xgraphics/xpm.go contains a small decoder for XPM (X PixMap) images, which
+are still used by many icon themes and in /usr/share/pixmaps.
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	image.RegisterFormat("xpm", "/* XPM */", decodeXPM, decodeXPMConfig)
+}
+
+// xpmColors maps the color names that are common in XPM files to colors.
+// Other names from rgb.txt are not supported.
+var xpmColors = map[string]color.NRGBA{
+	"none":        {},
+	"transparent": {},
+	"black":       {0, 0, 0, 0xff},
+	"white":       {0xff, 0xff, 0xff, 0xff},
+	"red":         {0xff, 0, 0, 0xff},
+	"green":       {0, 0xff, 0, 0xff},
+	"blue":        {0, 0, 0xff, 0xff},
+	"yellow":      {0xff, 0xff, 0, 0xff},
+	"gray":        {0xbe, 0xbe, 0xbe, 0xff},
+	"grey":        {0xbe, 0xbe, 0xbe, 0xff},
+}
+
+// xpmStrings reads all of the C strings in an XPM file, which is all that
+// matters in it. Escape sequences aren't used in XPM files.
+func xpmStrings(r io.Reader) ([]string, error) {
+	var strs []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for {
+			start := strings.IndexByte(line, '"')
+			if start < 0 {
+				break
+			}
+			end := strings.IndexByte(line[start+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("xpm: Unterminated string.")
+			}
+			strs = append(strs, line[start+1:start+1+end])
+			line = line[start+end+2:]
+		}
+	}
+	return strs, scanner.Err()
+}
+
+// xpmHeader parses the width, height, number of colors and characters per
+// pixel from the first string of an XPM file.
+func xpmHeader(strs []string) (w, h, ncolors, cpp int, err error) {
+	if len(strs) == 0 {
+		return 0, 0, 0, 0, fmt.Errorf("xpm: Missing header.")
+	}
+	fields := strings.Fields(strs[0])
+	if len(fields) < 4 {
+		return 0, 0, 0, 0, fmt.Errorf("xpm: Invalid header %q.", strs[0])
+	}
+	vals := make([]int, 4)
+	for i := range vals {
+		vals[i], err = strconv.Atoi(fields[i])
+		if err != nil || vals[i] < 0 {
+			return 0, 0, 0, 0, fmt.Errorf("xpm: Invalid header %q.", strs[0])
+		}
+	}
+	if vals[3] == 0 {
+		return 0, 0, 0, 0, fmt.Errorf("xpm: Invalid header %q.", strs[0])
+	}
+	return vals[0], vals[1], vals[2], vals[3], nil
+}
+
+// xpmColor parses the color of a color table entry. The "c" (color) key is
+// preferred, followed by "g" (gray scale) and "m" (mono).
+func xpmColor(spec string) (color.NRGBA, error) {
+	fields := strings.Fields(spec)
+	values := make(map[string]string)
+	for i := 0; i+1 < len(fields); i += 2 {
+		values[fields[i]] = fields[i+1]
+	}
+
+	for _, key := range []string{"c", "g", "g4", "m"} {
+		val, ok := values[key]
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(val, "#") {
+			return xpmHexColor(val[1:])
+		}
+		if c, ok := xpmColors[strings.ToLower(val)]; ok {
+			return c, nil
+		}
+		return color.NRGBA{}, fmt.Errorf("xpm: Unsupported color %q.", val)
+	}
+	return color.NRGBA{}, fmt.Errorf("xpm: No color in %q.", spec)
+}
+
+// xpmHexColor parses a hexadecimal color with 1 to 4 digits per component.
+func xpmHexColor(hex string) (color.NRGBA, error) {
+	if len(hex) == 0 || len(hex)%3 != 0 || len(hex) > 12 {
+		return color.NRGBA{}, fmt.Errorf("xpm: Invalid color #%s.", hex)
+	}
+	digits := len(hex) / 3
+	var comps [3]uint8
+	for i := range comps {
+		v, err := strconv.ParseUint(hex[i*digits:(i+1)*digits], 16, 16)
+		if err != nil {
+			return color.NRGBA{}, fmt.Errorf("xpm: Invalid color #%s.", hex)
+		}
+		// Scale the component to 8 bits.
+		comps[i] = uint8(v * 0xff / (1<<uint(4*digits) - 1))
+	}
+	return color.NRGBA{comps[0], comps[1], comps[2], 0xff}, nil
+}
+
+// decodeXPM decodes an XPM image.
+func decodeXPM(r io.Reader) (image.Image, error) {
+	strs, err := xpmStrings(r)
+	if err != nil {
+		return nil, err
+	}
+	w, h, ncolors, cpp, err := xpmHeader(strs)
+	if err != nil {
+		return nil, err
+	}
+	if len(strs) < 1+ncolors+h {
+		return nil, fmt.Errorf("xpm: Expected %d colors and %d rows, but "+
+			"the file is too short.", ncolors, h)
+	}
+
+	colors := make(map[string]color.NRGBA, ncolors)
+	for _, entry := range strs[1 : 1+ncolors] {
+		if len(entry) < cpp {
+			return nil, fmt.Errorf("xpm: Invalid color entry %q.", entry)
+		}
+		c, err := xpmColor(entry[cpp:])
+		if err != nil {
+			return nil, err
+		}
+		colors[entry[:cpp]] = c
+	}
+
+	// Check every row before allocating the image, so that a bogus header
+	// can't make us allocate more memory than the file could describe.
+	rows := strs[1+ncolors : 1+ncolors+h]
+	for y, row := range rows {
+		if len(row)/cpp < w {
+			return nil, fmt.Errorf("xpm: Row %d is too short.", y)
+		}
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y, row := range rows {
+		for x := 0; x < w; x++ {
+			c, ok := colors[row[x*cpp:(x+1)*cpp]]
+			if !ok {
+				return nil, fmt.Errorf("xpm: Undefined pixel %q in row %d.",
+					row[x*cpp:(x+1)*cpp], y)
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img, nil
+}
+
+// decodeXPMConfig returns the dimensions of an XPM image.
+func decodeXPMConfig(r io.Reader) (image.Config, error) {
+	strs, err := xpmStrings(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	w, h, _, _, err := xpmHeader(strs)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: w, Height: h}, nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/jezek/xgbutil/keybind"
 	"github.com/jezek/xgbutil/mousebind"
 	"github.com/jezek/xgbutil/xevent"
+	"github.com/jezek/xgbutil/xrect"
 	"github.com/jezek/xgbutil/xwindow"
 )
 
@@ -48,6 +49,48 @@ func (im *Image) XSurfaceSet(wid xproto.Window) error {
 	return nil
 }
 
+// Tile creates a new image that repeats this image to cover 'bounds' (in the
+// coordinates of 'wid'), and sets it as the background of 'wid'. The copy
+// starting at the top-left corner of 'bounds' is complete, and copies at the
+// right and bottom edges are cropped if the size of 'bounds' isn't a multiple
+// of the size of the image. (Which is also the case when the image is larger
+// than 'bounds'.)
+// X repeats the background pixmap if the window is larger than the bottom
+// right corner of 'bounds'.
+// The new image is returned, and should be destroyed once it is no longer the
+// background of 'wid'.
+func (im *Image) Tile(wid xproto.Window, bounds xrect.Rect) (*Image, error) {
+	bx, by, bw, bh := xrect.RectPieces(bounds)
+	tw, th := bx+bw, by+bh
+	if bw <= 0 || bh <= 0 || tw <= 0 || th <= 0 || im.Rect.Empty() {
+		return nil, fmt.Errorf("Tile: Cannot tile an image of size %s "+
+			"into %s.", im.Rect.Size(), bounds)
+	}
+
+	// A negative remainder must wrap around.
+	mod := func(a, b int) int {
+		return ((a % b) + b) % b
+	}
+
+	w, h := im.Rect.Dx(), im.Rect.Dy()
+	tiled := New(im.X, image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		sy := im.Rect.Min.Y + mod(y-by, h)
+		for x := 0; x < tw; x++ {
+			sx := im.Rect.Min.X + mod(x-bx, w)
+			si, di := im.PixOffset(sx, sy), tiled.PixOffset(x, y)
+			copy(tiled.Pix[di:di+4], im.Pix[si:si+4])
+		}
+	}
+
+	if err := tiled.XSurfaceSet(wid); err != nil {
+		return nil, err
+	}
+	tiled.XDraw()
+	tiled.XPaint(wid)
+	return tiled, nil
+}
+
 // CreatePixmap allocates an X resource identifier for a pixmap. (It does not
 // do any drawing.) You only need to call this if you're using XDraw/XExpPaint.
 // If you're using XSurfaceSet/XDraw/XPaint, then CreatePixmap is called for
@@ -111,13 +154,35 @@ func (im *Image) XExpPaint(wid xproto.Window, x, y int) {
 // sub-image and only that sub-image.
 func (im *Image) XPaintRects(wid xproto.Window, rects ...image.Rectangle) {
 	for _, rect := range rects {
-		if si := im.SubImage(rect).(*Image); si != nil {
-			si.XDraw()
-		}
+		im.XDrawSub(rect)
 	}
 	im.XPaint(wid)
 }
 
+// XDrawSub is like XDraw, but only the pixels inside 'r' are written to the
+// pixmap. This is a shortcut for calling XDraw on the sub-image for 'r'.
+// Nothing is drawn if 'r' doesn't overlap the image.
+func (im *Image) XDrawSub(r image.Rectangle) {
+	if si, ok := im.SubImage(r).(*Image); ok {
+		si.XDraw()
+	}
+}
+
+// XPaintSub is like XPaint, but only the pixels inside 'r' are drawn and
+// only the area of the window covered by 'r' is repainted. This is useful for
+// small, frequent updates, like a blinking cursor or a progress bar.
+// Like XPaint, this assumes the image is the background pixmap of 'wid'.
+// (See XSurfaceSet.)
+func (im *Image) XPaintSub(wid xproto.Window, r image.Rectangle) {
+	r = r.Intersect(im.Rect)
+	if r.Empty() {
+		return
+	}
+	im.XDrawSub(r)
+	xproto.ClearArea(im.X.Conn(), false, wid, int16(r.Min.X), int16(r.Min.Y),
+		uint16(r.Dx()), uint16(r.Dy()))
+}
+
 // XDraw will write the contents of Image to a pixmap.
 // Note that this is more like a buffer. Drawing does not put the contents
 // on the screen.
@@ -142,6 +207,10 @@ func (im *Image) XDrawChecked() error {
 }
 
 func (im *Image) xdraw(checked bool) error {
+	if im.shm != nil && im.shm.attached {
+		return im.xdrawShm(checked)
+	}
+
 	width, height := im.Rect.Dx(), im.Rect.Dy()
 
 	// Put the raw image data into its own slice.
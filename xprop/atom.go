@@ -53,6 +53,52 @@ func Atom(xu *xgbutil.XUtil, name string,
 	return reply.Atom, nil
 }
 
+// AtomBulk interns several atoms at once. Atoms that aren't cached are
+// interned by sending all of the requests before waiting for any of the
+// replies, which saves a round trip for each atom. The atoms are returned in
+// the same order as 'names'.
+// Like Atm, new atoms are created if they do not already exist.
+func AtomBulk(xu *xgbutil.XUtil, names []string) ([]xproto.Atom, error) {
+	aids := make([]xproto.Atom, len(names))
+	cookies := make([]xproto.InternAtomCookie, len(names))
+	pending := make([]int, 0, len(names))
+	for i, name := range names {
+		if aid, ok := atomGet(xu, name); ok {
+			aids[i] = aid
+			continue
+		}
+		cookies[i] = xproto.InternAtom(xu.Conn(), false,
+			uint16(len(name)), name)
+		pending = append(pending, i)
+	}
+
+	var firstErr error
+	for _, i := range pending {
+		// Every reply must be read, even after an error.
+		reply, err := cookies[i].Reply()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("AtomBulk: Error interning atom "+
+					"'%s': %s", names[i], err)
+			}
+			continue
+		}
+		if reply.Atom == 0 {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("AtomBulk: '%s' returned an "+
+					"identifier of 0.", names[i])
+			}
+			continue
+		}
+		cacheAtom(xu, names[i], reply.Atom)
+		aids[i] = reply.Atom
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return aids, nil
+}
+
 // AtomName fetches a string representation of an ATOM given its integer id.
 func AtomName(xu *xgbutil.XUtil, aid xproto.Atom) (string, error) {
 	// Check the cache first
@@ -0,0 +1,274 @@
+package xprop
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xevent"
+)
+
+// SelectionFun is the type of function run by ServeSelection to get the
+// contents of a selection converted to 'target' (i.e., "UTF8_STRING"). If the
+// selection can't be converted, it should return false.
+type SelectionFun func(xu *xgbutil.XUtil, target string) ([]byte, bool)
+
+// OwnSelection makes 'win' the owner of the selection 'sel' (i.e., "PRIMARY"
+// or "CLIPBOARD"). 'tstamp' should be the time of the event that caused the
+// selection to be made, like xu.TimeGet(). (Not CurrentTime.)
+// An error is returned if 'win' didn't become the owner, which happens when
+// the selection was taken by another client at a later time.
+func OwnSelection(xu *xgbutil.XUtil, sel string, win xproto.Window,
+	tstamp xproto.Timestamp) error {
+
+	selAtom, err := Atm(xu, sel)
+	if err != nil {
+		return err
+	}
+
+	err = xproto.SetSelectionOwnerChecked(xu.Conn(), win, selAtom,
+		tstamp).Check()
+	if err != nil {
+		return fmt.Errorf("OwnSelection: Could not set the owner of %s: %s",
+			sel, err)
+	}
+
+	reply, err := xproto.GetSelectionOwner(xu.Conn(), selAtom).Reply()
+	if err != nil {
+		return fmt.Errorf("OwnSelection: Could not get the owner of %s: %s",
+			sel, err)
+	}
+	if reply.Owner != win {
+		return fmt.Errorf("OwnSelection: Window %x did not become the owner "+
+			"of %s. (The owner is %x.)", win, sel, reply.Owner)
+	}
+	return nil
+}
+
+// ServeSelection answers requests for the contents of the selection 'sel'
+// that are sent to its owner 'win'. Requests for any target in 'targets' are
+// answered with the data returned by 'f', which is stored as 8 bit data
+// with the target as its type. Requests for the TARGETS target are answered
+// with 'targets' (plus TARGETS itself), and any other request is refused.
+// The data must fit in a single request, since the INCR protocol for large
+// transfers isn't supported.
+//
+// ServeSelection should be called once, and the selection should then be
+// taken with OwnSelection. Connect a function to SelectionClear events on
+// 'win' to find out when another client takes the selection. The handler can
+// be removed with xevent.Detach.
+func ServeSelection(xu *xgbutil.XUtil, sel string, win xproto.Window,
+	targets []string, f SelectionFun) error {
+
+	selAtom, err := Atm(xu, sel)
+	if err != nil {
+		return err
+	}
+	targetsAtom, err := Atm(xu, "TARGETS")
+	if err != nil {
+		return err
+	}
+
+	supported := make(map[xproto.Atom]string, len(targets))
+	targetAtoms := []xproto.Atom{targetsAtom}
+	for _, target := range targets {
+		atom, err := Atm(xu, target)
+		if err != nil {
+			return err
+		}
+		supported[atom] = target
+		targetAtoms = append(targetAtoms, atom)
+	}
+
+	xevent.SelectionRequestFun(
+		func(xu *xgbutil.XUtil, ev xevent.SelectionRequestEvent) {
+			if ev.Selection != selAtom {
+				return
+			}
+
+			// Obsolete clients don't give a property, in which case the
+			// target is used as the property.
+			prop := ev.Property
+			if prop == 0 {
+				prop = ev.Target
+			}
+
+			var err error
+			if ev.Target == targetsAtom {
+				buf := make([]byte, 4*len(targetAtoms))
+				for i, atom := range targetAtoms {
+					xgb.Put32(buf[i*4:], uint32(atom))
+				}
+				err = xproto.ChangePropertyChecked(xu.Conn(),
+					xproto.PropModeReplace, ev.Requestor, prop,
+					xproto.AtomAtom, 32, uint32(len(targetAtoms)),
+					buf).Check()
+			} else if target, ok := supported[ev.Target]; ok {
+				data, ok := f(xu, target)
+				if !ok {
+					prop = 0
+				} else {
+					err = xproto.ChangePropertyChecked(xu.Conn(),
+						xproto.PropModeReplace, ev.Requestor, prop,
+						ev.Target, 8, uint32(len(data)), data).Check()
+				}
+			} else {
+				prop = 0
+			}
+			if err != nil {
+				xgbutil.Logger.Printf("ServeSelection: Could not store %s "+
+					"on window %x: %s", sel, ev.Requestor, err)
+				prop = 0
+			}
+
+			notify := xproto.SelectionNotifyEvent{
+				Time:      ev.Time,
+				Requestor: ev.Requestor,
+				Selection: ev.Selection,
+				Target:    ev.Target,
+				Property:  prop,
+			}
+			xproto.SendEvent(xu.Conn(), false, ev.Requestor, 0,
+				string(notify.Bytes()))
+		}).Connect(xu, win)
+	return nil
+}
+
+// GetSelection asks the owner of the selection 'sel' (i.e., "CLIPBOARD") to
+// convert it to 'target' (i.e., "UTF8_STRING"), and returns the converted
+// data. Large selections that the owner sends in chunks with the INCR
+// protocol are put back together.
+// The conversion is stored in a property on a temporary window, and an error
+// is returned if the owner refuses the conversion, or doesn't answer (or send
+// the next chunk) within 'timeout'.
+//
+// GetSelection waits for events that are received by the main event loop. It
+// must therefore be called while the main event loop is running in another
+// goroutine, and never from inside an event handler.
+func GetSelection(xu *xgbutil.XUtil, sel, target string,
+	timeout time.Duration) ([]byte, error) {
+
+	selAtom, err := Atm(xu, sel)
+	if err != nil {
+		return nil, err
+	}
+	targetAtom, err := Atm(xu, target)
+	if err != nil {
+		return nil, err
+	}
+	propAtom, err := Atm(xu, "_XGBUTIL_SELECTION")
+	if err != nil {
+		return nil, err
+	}
+	incrAtom, err := Atm(xu, "INCR")
+	if err != nil {
+		return nil, err
+	}
+
+	win, err := xproto.NewWindowId(xu.Conn())
+	if err != nil {
+		return nil, err
+	}
+	err = xproto.CreateWindowChecked(xu.Conn(), 0, win, xu.RootWin(),
+		0, 0, 1, 1, 0, xproto.WindowClassInputOnly, 0,
+		xproto.CwEventMask, []uint32{xproto.EventMaskPropertyChange}).Check()
+	if err != nil {
+		return nil, fmt.Errorf("GetSelection: Could not create window: %s",
+			err)
+	}
+	defer xproto.DestroyWindow(xu.Conn(), win)
+	defer xevent.Detach(xu, win)
+
+	notified := make(chan xevent.SelectionNotifyEvent, 1)
+	newValues := make(chan struct{}, 64)
+	xevent.SelectionNotifyFun(
+		func(xu *xgbutil.XUtil, ev xevent.SelectionNotifyEvent) {
+			if ev.Selection != selAtom {
+				return
+			}
+			select {
+			case notified <- ev:
+			default:
+			}
+		}).Connect(xu, win)
+	xevent.PropertyNotifyFun(
+		func(xu *xgbutil.XUtil, ev xevent.PropertyNotifyEvent) {
+			if ev.Atom != propAtom || ev.State != xproto.PropertyNewValue {
+				return
+			}
+			select {
+			case newValues <- struct{}{}:
+			default:
+			}
+		}).Connect(xu, win)
+
+	err = xproto.ConvertSelectionChecked(xu.Conn(), win, selAtom, targetAtom,
+		propAtom, xu.TimeGet()).Check()
+	if err != nil {
+		return nil, fmt.Errorf("GetSelection: Could not convert %s to %s: %s",
+			sel, target, err)
+	}
+
+	var ev xevent.SelectionNotifyEvent
+	select {
+	case ev = <-notified:
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("GetSelection: The owner of %s did not "+
+			"answer within %s.", sel, timeout)
+	}
+	if ev.Property == 0 {
+		return nil, fmt.Errorf("GetSelection: The owner of %s refused to "+
+			"convert it to %s.", sel, target)
+	}
+
+	// Any new values seen so far are from storing the converted selection,
+	// so they must be forgotten before the INCR transfer starts.
+	for len(newValues) > 0 {
+		<-newValues
+	}
+	reply, err := getSelectionProp(xu, win, propAtom)
+	if err != nil {
+		return nil, err
+	}
+	if reply.Type != incrAtom {
+		return reply.Value, nil
+	}
+
+	// Deleting the INCR property asked the owner for the first chunk. Each
+	// chunk is deleted after it is read to ask for the next, until an empty
+	// chunk marks the end.
+	var data []byte
+	for {
+		select {
+		case <-newValues:
+		case <-time.After(timeout):
+			return nil, fmt.Errorf("GetSelection: The owner of %s did not "+
+				"send the next chunk within %s.", sel, timeout)
+		}
+		reply, err := getSelectionProp(xu, win, propAtom)
+		if err != nil {
+			return nil, err
+		}
+		if len(reply.Value) == 0 {
+			return data, nil
+		}
+		data = append(data, reply.Value...)
+	}
+}
+
+// getSelectionProp reads and deletes the property holding a converted
+// selection.
+func getSelectionProp(xu *xgbutil.XUtil, win xproto.Window,
+	prop xproto.Atom) (*xproto.GetPropertyReply, error) {
+
+	reply, err := xproto.GetProperty(xu.Conn(), true, win, prop,
+		xproto.GetPropertyTypeAny, 0, (1<<32)-1).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("GetSelection: Could not read the selection: "+
+			"%s", err)
+	}
+	return reply, nil
+}
@@ -0,0 +1,88 @@
+package xprop
+
+import (
+	"bytes"
+
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xevent"
+)
+
+// WatchFun is the type of function run by Watch when a property changes.
+type WatchFun func(xu *xgbutil.XUtil, reply *xproto.GetPropertyReply)
+
+// Watch runs 'f' with the new value of the property 'atom' on 'win' every
+// time it changes. If the property is deleted (or can't be read), 'f' is run
+// with a nil reply.
+// PropertyChange events are added to the event mask of 'win', and 'f' is run
+// from inside the main event loop. The watch can be removed with
+// xevent.Detach, which removes every callback on the window.
+func Watch(xu *xgbutil.XUtil, win xproto.Window, atom string,
+	f WatchFun) error {
+
+	atomId, err := Atm(xu, atom)
+	if err != nil {
+		return err
+	}
+
+	err = xevent.SelectInputAdd(xu, win, xproto.EventMaskPropertyChange)
+	if err != nil {
+		return err
+	}
+
+	xevent.PropertyNotifyFun(
+		func(xu *xgbutil.XUtil, ev xevent.PropertyNotifyEvent) {
+			if ev.Atom != atomId {
+				return
+			}
+			if ev.State == xproto.PropertyDelete {
+				f(xu, nil)
+				return
+			}
+			reply, err := GetProperty(xu, win, atom)
+			if err != nil {
+				reply = nil
+			}
+			f(xu, reply)
+		}).Connect(xu, win)
+	return nil
+}
+
+// WatchDiffFun is the type of function run by WatchDiff when a property
+// changes. Either reply is nil if the property didn't exist (or couldn't be
+// read) at that time.
+type WatchDiffFun func(xu *xgbutil.XUtil, prev, cur *xproto.GetPropertyReply)
+
+// WatchDiff is just like Watch, except 'f' is run with both the previous and
+// the new value of the property, so that it can find out what changed. (i.e.,
+// which states were added to or removed from _NET_WM_STATE.) The current
+// value is read when WatchDiff is called, and is the previous value for the
+// first change.
+// 'f' is not run if a change leaves the property with the same value.
+func WatchDiff(xu *xgbutil.XUtil, win xproto.Window, atom string,
+	f WatchDiffFun) error {
+
+	prev, err := GetProperty(xu, win, atom)
+	if err != nil {
+		prev = nil
+	}
+	return Watch(xu, win, atom,
+		func(xu *xgbutil.XUtil, cur *xproto.GetPropertyReply) {
+			if sameProperty(prev, cur) {
+				return
+			}
+			old := prev
+			prev = cur
+			f(xu, old, cur)
+		})
+}
+
+// sameProperty reports whether two property replies hold the same value.
+func sameProperty(a, b *xproto.GetPropertyReply) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Type == b.Type && a.Format == b.Format &&
+		bytes.Equal(a.Value, b.Value)
+}
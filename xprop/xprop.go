@@ -1,7 +1,10 @@
 package xprop
 
 import (
+	"bytes"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/jezek/xgb"
 	"github.com/jezek/xgb/xproto"
@@ -34,6 +37,46 @@ func GetProperty(xu *xgbutil.XUtil, win xproto.Window, atom string) (
 	return reply, nil
 }
 
+// GetMany is like GetProperty, but fetches several properties of the same
+// window at once. All of the requests are sent before waiting for any of the
+// replies, which saves a round trip for each property.
+// The returned map is keyed by property name. Properties that don't exist on
+// the window are not in the map.
+func GetMany(xu *xgbutil.XUtil, win xproto.Window, atoms []string) (
+	map[string]*xproto.GetPropertyReply, error) {
+
+	cookies := make([]xproto.GetPropertyCookie, len(atoms))
+	for i, atom := range atoms {
+		atomId, err := Atm(xu, atom)
+		if err != nil {
+			return nil, err
+		}
+		cookies[i] = xproto.GetProperty(xu.Conn(), false, win, atomId,
+			xproto.GetPropertyTypeAny, 0, (1<<32)-1)
+	}
+
+	replies := make(map[string]*xproto.GetPropertyReply, len(atoms))
+	var firstErr error
+	for i, cookie := range cookies {
+		// Every reply must be read, even after an error.
+		reply, err := cookie.Reply()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("GetMany: Error retrieving property "+
+					"'%s' on window %x: %s", atoms[i], win, err)
+			}
+			continue
+		}
+		if reply.Format != 0 {
+			replies[atoms[i]] = reply
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return replies, nil
+}
+
 // ChangeProperty abstracts the semi-nastiness of xgb.ChangeProperty.
 func ChangeProp(xu *xgbutil.XUtil, win xproto.Window, format byte, prop string,
 	typ string, data []byte) error {
@@ -53,6 +96,32 @@ func ChangeProp(xu *xgbutil.XUtil, win xproto.Window, format byte, prop string,
 		uint32(len(data)/(int(format)/8)), data).Check()
 }
 
+// DeleteProp removes the property 'prop' from the window. It is not an error
+// if the window doesn't have the property.
+func DeleteProp(xu *xgbutil.XUtil, win xproto.Window, prop string) error {
+	propAtom, err := Atm(xu, prop)
+	if err != nil {
+		return err
+	}
+	return xproto.DeletePropertyChecked(xu.Conn(), win, propAtom).Check()
+}
+
+// ChangePropStr sets the property 'prop' to the string 's', which is UTF-8
+// like every Go string. If every character of 's' is in Latin-1, the
+// property has type STRING and 's' is converted to Latin-1, as the ICCCM
+// requires. Otherwise, STRING can't represent 's', so the property has type
+// UTF8_STRING instead. PropValStr reads both back as 's'.
+func ChangePropStr(xu *xgbutil.XUtil, win xproto.Window, prop,
+	s string) error {
+
+	for _, r := range s {
+		if r > 0xff {
+			return ChangeProp(xu, win, 8, prop, "UTF8_STRING", []byte(s))
+		}
+	}
+	return ChangeProp(xu, win, 8, prop, "STRING", UTF8ToLatin1(s))
+}
+
 // ChangeProperty32 makes changing 32 bit formatted properties easier
 // by constructing the raw X data for you.
 func ChangeProp32(xu *xgbutil.XUtil, win xproto.Window, prop string, typ string,
@@ -66,6 +135,25 @@ func ChangeProp32(xu *xgbutil.XUtil, win xproto.Window, prop string, typ string,
 	return ChangeProp(xu, win, 32, prop, typ, buf)
 }
 
+// ChangeCardinals replaces the property 'prop' with a list of CARDINAL
+// values. An empty list sets the property to an empty value, which is not the
+// same as deleting it. (Use DeleteProp for that.)
+// The event mask of 'win' is left alone. To be told about the resulting
+// PropertyNotify event, use Watch or xevent.SelectInputAdd.
+func ChangeCardinals(xu *xgbutil.XUtil, win xproto.Window, prop string,
+	vals []uint) error {
+
+	return ChangeProp32(xu, win, prop, "CARDINAL", vals...)
+}
+
+// ChangeWindows replaces the property 'prop' with a list of WINDOW values.
+// It is otherwise the same as ChangeCardinals.
+func ChangeWindows(xu *xgbutil.XUtil, win xproto.Window, prop string,
+	wins []xproto.Window) error {
+
+	return ChangeProp32(xu, win, prop, "WINDOW", WindowToInt(wins)...)
+}
+
 // WindowToUint is a covenience function for converting []xproto.Window
 // to []uint.
 func WindowToInt(ids []xproto.Window) []uint {
@@ -232,6 +320,9 @@ func PropValNum64(reply *xproto.GetPropertyReply, err error) (int64, error) {
 // PropValStr transforms a GetPropertyReply struct into a string.
 // Useful when the property value is a null terminated string represented
 // by integers. Also must be 8 bit format.
+// If the type of the property is STRING (like WM_NAME), its value is
+// converted from Latin-1 to UTF-8, as required by the ICCCM. Any other type
+// is returned as is. (Use PropValUTF8 for UTF8_STRING properties.)
 func PropValStr(reply *xproto.GetPropertyReply, err error) (string, error) {
 	if err != nil {
 		return "", err
@@ -240,12 +331,16 @@ func PropValStr(reply *xproto.GetPropertyReply, err error) (string, error) {
 		return "", fmt.Errorf("PropValStr: Expected format 8 but got %d",
 			reply.Format)
 	}
+	if reply.Type == xproto.AtomString {
+		return latin1ToUTF8(reply.Value), nil
+	}
 	return string(reply.Value), nil
 }
 
 // PropValStrs is the same as PropValStr, except that it returns a slice
 // of strings. The raw byte string is a sequence of null terminated strings,
-// which is translated into a slice of strings.
+// which is translated into a slice of strings. Trailing empty strings are
+// dropped.
 func PropValStrs(reply *xproto.GetPropertyReply, err error) ([]string, error) {
 	if err != nil {
 		return nil, err
@@ -256,15 +351,89 @@ func PropValStrs(reply *xproto.GetPropertyReply, err error) ([]string, error) {
 	}
 
 	var strs []string
-	sstart := 0
-	for i, c := range reply.Value {
-		if c == 0 {
-			strs = append(strs, string(reply.Value[sstart:i]))
-			sstart = i + 1
+	for _, s := range splitNul(reply.Value) {
+		if reply.Type == xproto.AtomString {
+			strs = append(strs, latin1ToUTF8(s))
+		} else {
+			strs = append(strs, string(s))
 		}
 	}
-	if sstart < int(reply.ValueLen) {
-		strs = append(strs, string(reply.Value[sstart:]))
+	return strs, nil
+}
+
+// PropValUTF8 transforms a GetPropertyReply struct into a string, decoding
+// the value as UTF-8. This is what properties of type UTF8_STRING (like
+// _NET_WM_NAME) contain. Invalid UTF-8 sequences are replaced with the
+// Unicode replacement character, and a trailing null byte is removed. Must be
+// 8 bit format.
+func PropValUTF8(reply *xproto.GetPropertyReply, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	if reply.Format != 8 {
+		return "", fmt.Errorf("PropValUTF8: Expected format 8 but got %d",
+			reply.Format)
+	}
+	return validUTF8(bytes.TrimSuffix(reply.Value, []byte{0})), nil
+}
+
+// PropValUTF8s is the same as PropValUTF8, except that it returns a slice of
+// strings. The raw byte string is a sequence of null terminated strings,
+// which is translated into a slice of strings. Trailing empty strings are
+// dropped.
+func PropValUTF8s(reply *xproto.GetPropertyReply,
+	err error) ([]string, error) {
+
+	if err != nil {
+		return nil, err
+	}
+	if reply.Format != 8 {
+		return nil, fmt.Errorf("PropValUTF8s: Expected format 8 but got %d",
+			reply.Format)
+	}
+
+	var strs []string
+	for _, s := range splitNul(reply.Value) {
+		strs = append(strs, validUTF8(s))
 	}
 	return strs, nil
 }
+
+// splitNul splits 'b' on null bytes, dropping any trailing empty strings.
+func splitNul(b []byte) [][]byte {
+	parts := bytes.Split(b, []byte{0})
+	for len(parts) > 0 && len(parts[len(parts)-1]) == 0 {
+		parts = parts[:len(parts)-1]
+	}
+	return parts
+}
+
+// latin1ToUTF8 converts a Latin-1 (ISO 8859-1) string to UTF-8. Every byte
+// is the Unicode code point of the same value.
+func latin1ToUTF8(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// UTF8ToLatin1 converts a UTF-8 string to Latin-1 (ISO 8859-1), which is
+// the encoding of properties of type STRING. Characters that aren't in
+// Latin-1 are replaced with '?'.
+func UTF8ToLatin1(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xff {
+			r = '?'
+		}
+		b = append(b, byte(r))
+	}
+	return b
+}
+
+// validUTF8 converts 'b' to a string, replacing invalid UTF-8 sequences with
+// the Unicode replacement character.
+func validUTF8(b []byte) string {
+	return strings.ToValidUTF8(string(b), string(utf8.RuneError))
+}
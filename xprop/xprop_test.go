@@ -0,0 +1,74 @@
+package xprop_test
+
+import (
+	"testing"
+
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xprop"
+)
+
+func TestChangeCardinals(t *testing.T) {
+	xu, m, err := xgbutil.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xu.Conn().Close()
+
+	for _, vals := range [][]uint{{}, {1, 2, 3}, nil} {
+		err := xprop.ChangeCardinals(xu, xu.RootWin(), "_TEST", vals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nums, err := xprop.PropValNums(
+			xprop.GetProperty(xu, xu.RootWin(), "_TEST"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nums) != len(vals) {
+			t.Fatalf("Expected %v, but got %v.", vals, nums)
+		}
+		for i := range vals {
+			if nums[i] != vals[i] {
+				t.Fatalf("Expected %v, but got %v.", vals, nums)
+			}
+		}
+	}
+
+	// The setter must not touch the event mask of the window.
+	for _, req := range m.Requests() {
+		if req.Opcode == 2 || req.Opcode == 3 { // {Change,Get}WindowAttributes
+			t.Fatalf("Expected no window attribute requests, but got "+
+				"opcode %d.", req.Opcode)
+		}
+	}
+}
+
+func TestChangeWindows(t *testing.T) {
+	xu, _, err := xgbutil.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xu.Conn().Close()
+
+	for _, wins := range [][]xproto.Window{{}, {0x400001, 0x400002}, nil} {
+		err := xprop.ChangeWindows(xu, xu.RootWin(), "_TEST", wins)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids, err := xprop.PropValWindows(
+			xprop.GetProperty(xu, xu.RootWin(), "_TEST"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ids) != len(wins) {
+			t.Fatalf("Expected %v, but got %v.", wins, ids)
+		}
+		for i := range wins {
+			if ids[i] != wins[i] {
+				t.Fatalf("Expected %v, but got %v.", wins, ids)
+			}
+		}
+	}
+}
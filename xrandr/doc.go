@@ -0,0 +1,14 @@
+/*
+Package xrandr provides convenience functions to retrieve the layout of all
+active monitors using the RandR extension. Unlike Xinerama, RandR 1.5 monitors
+have names (like "HDMI-1"), and one of them can be marked as the primary
+monitor.
+
+If the X server doesn't support RandR 1.5, the Xinerama extension is used
+instead. In that case, monitors have no names or outputs, and none of them is
+the primary monitor.
+
+Watch can be used to find out when the monitor layout changes, like when a
+monitor is plugged in or unplugged.
+*/
+package xrandr
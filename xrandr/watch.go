@@ -0,0 +1,64 @@
+package xrandr
+
+import (
+	"time"
+
+	"github.com/jezek/xgb/randr"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xevent"
+)
+
+// WatchDelay is how long Watch waits after the last RandR event before
+// reporting the new monitor layout. Docking stations and some drivers send a
+// burst of events for a single change, and only the final layout is
+// interesting.
+var WatchDelay = 250 * time.Millisecond
+
+// Watch runs 'f' with the new list of active monitors (see Monitors) every
+// time the monitor layout changes. (i.e., a monitor is plugged in, unplugged
+// or its resolution changes.) 'f' is run from inside the main event loop.
+//
+// RandR events are still processed as usual after Watch has seen them, so
+// other callbacks and hooks can respond to them too.
+// The returned function stops watching. It must be called from inside the
+// main event loop (or while it isn't running).
+func Watch(xu *xgbutil.XUtil, f func(monitors []Monitor)) (func(), error) {
+	if !xu.ExtInitialized("RANDR") {
+		if err := randr.Init(xu.Conn()); err != nil {
+			return nil, err
+		}
+	}
+	err := randr.SelectInputChecked(xu.Conn(), xu.RootWin(),
+		randr.NotifyMaskScreenChange|randr.NotifyMaskCrtcChange|
+			randr.NotifyMaskOutputChange).Check()
+	if err != nil {
+		return nil, err
+	}
+
+	timer, err := xevent.NewTimer(xu, func(xu *xgbutil.XUtil) {
+		monitors, err := Monitors(xu)
+		if err != nil {
+			xgbutil.Logger.Printf("Watch: Could not get monitors: %s", err)
+			return
+		}
+		f(monitors)
+	})
+	if err != nil {
+		return nil, err
+	}
+	hook := xevent.ConnectHookHandle(xu, xevent.HookFun(
+		func(xu *xgbutil.XUtil, ev interface{}) bool {
+			switch ev.(type) {
+			case randr.ScreenChangeNotifyEvent, randr.NotifyEvent:
+				timer.Reset(xu, WatchDelay)
+			}
+			return true
+		}))
+
+	stop := func() {
+		timer.Detach(xu)
+		xevent.DetachHandle(xu, hook)
+	}
+	return stop, nil
+}
@@ -0,0 +1,40 @@
+package xrandr_test
+
+import (
+	"testing"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xrandr"
+)
+
+func TestWatchStop(t *testing.T) {
+	xu, m, err := xgbutil.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xu.Conn().Close()
+
+	// Pretend that RANDR is present.
+	m.ReplySet(98, func(req xgbutil.MockRequest) []byte {
+		reply := make([]byte, 32)
+		reply[8], reply[9] = 1, 140    // present, major opcode
+		reply[10], reply[11] = 89, 147 // first event, first error
+		return reply
+	})
+
+	for i := 0; i < 2; i++ {
+		stop, err := xrandr.Watch(xu, func(monitors []xrandr.Monitor) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(xu.Hooks) != 1 {
+			t.Fatalf("Expected 1 hook while watching, but got %d.",
+				len(xu.Hooks))
+		}
+		stop()
+		if len(xu.Hooks) != 0 {
+			t.Fatalf("Expected no hooks after stopping, but got %d.",
+				len(xu.Hooks))
+		}
+	}
+}
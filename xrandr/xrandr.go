@@ -0,0 +1,120 @@
+package xrandr
+
+import (
+	"fmt"
+
+	"github.com/jezek/xgb/randr"
+
+	"github.com/jezek/xgbutil"
+	"github.com/jezek/xgbutil/xinerama"
+	"github.com/jezek/xgbutil/xprop"
+	"github.com/jezek/xgbutil/xrect"
+)
+
+// Monitor describes a single active monitor.
+type Monitor struct {
+	// Name is the name of the monitor. (Usually the name of its output, like
+	// "HDMI-1".)
+	Name string
+
+	// Rect is the geometry of the monitor in root window coordinates.
+	Rect xrect.Rect
+
+	// Primary is true for the primary monitor.
+	Primary bool
+
+	// Outputs and Crtcs are the RandR outputs that make up the monitor, and
+	// the CRTC driving each of them. (Or 0 if an output has no CRTC.)
+	Outputs []randr.Output
+	Crtcs   []randr.Crtc
+}
+
+// Monitors returns the list of active monitors, as reported by the
+// GetMonitors request of RandR 1.5.
+// If RandR 1.5 isn't available, the heads reported by Xinerama are returned
+// instead. (See xinerama.PhysicalHeads.)
+func Monitors(xu *xgbutil.XUtil) ([]Monitor, error) {
+	if err := initRandr(xu); err != nil {
+		if !xu.ExtInitialized("XINERAMA") {
+			return nil, fmt.Errorf("Monitors: Neither RandR 1.5 nor "+
+				"Xinerama are available: %s", err)
+		}
+		return xineramaMonitors(xu)
+	}
+
+	reply, err := randr.GetMonitors(xu.Conn(), xu.RootWin(), true).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("Monitors: Could not get monitors: %s", err)
+	}
+	resources, err := randr.GetScreenResourcesCurrent(xu.Conn(),
+		xu.RootWin()).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("Monitors: Could not get screen resources: %s",
+			err)
+	}
+
+	monitors := make([]Monitor, len(reply.Monitors))
+	for i, info := range reply.Monitors {
+		name, err := xprop.AtomName(xu, info.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		crtcs := make([]randr.Crtc, len(info.Outputs))
+		for j, output := range info.Outputs {
+			oinfo, err := randr.GetOutputInfo(xu.Conn(), output,
+				resources.ConfigTimestamp).Reply()
+			if err != nil {
+				return nil, fmt.Errorf("Monitors: Could not get information "+
+					"for output %d: %s", output, err)
+			}
+			crtcs[j] = oinfo.Crtc
+		}
+
+		monitors[i] = Monitor{
+			Name: name,
+			Rect: xrect.New(int(info.X), int(info.Y),
+				int(info.Width), int(info.Height)),
+			Primary: info.Primary,
+			Outputs: info.Outputs,
+			Crtcs:   crtcs,
+		}
+	}
+	return monitors, nil
+}
+
+// initRandr initializes the RandR extension if necessary, and returns an
+// error if the X server doesn't support version 1.5.
+func initRandr(xu *xgbutil.XUtil) error {
+	if !xu.ExtInitialized("RANDR") {
+		if err := randr.Init(xu.Conn()); err != nil {
+			return err
+		}
+	}
+
+	reply, err := randr.QueryVersion(xu.Conn(), 1, 5).Reply()
+	if err != nil {
+		return err
+	}
+	if reply.MajorVersion < 1 ||
+		(reply.MajorVersion == 1 && reply.MinorVersion < 5) {
+		return fmt.Errorf("RandR version 1.5 is required, but the X server "+
+			"only supports version %d.%d.",
+			reply.MajorVersion, reply.MinorVersion)
+	}
+	return nil
+}
+
+// xineramaMonitors returns a Monitor for each head reported by Xinerama.
+func xineramaMonitors(xu *xgbutil.XUtil) ([]Monitor, error) {
+	heads, err := xinerama.PhysicalHeads(xu)
+	if err != nil {
+		return nil, err
+	}
+
+	monitors := make([]Monitor, len(heads))
+	for i, head := range heads {
+		monitors[i] = Monitor{Rect: head}
+	}
+	return monitors, nil
+}
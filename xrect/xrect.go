@@ -149,12 +149,51 @@ func IntersectArea(r1 Rect, r2 Rect) int {
 	return 0
 }
 
+// Intersect returns the intersection of two rectangles, and whether they
+// overlap at all. If they don't, the rectangle returned is nil.
+func Intersect(r1 Rect, r2 Rect) (Rect, bool) {
+	x1, y1, w1, h1 := RectPieces(r1)
+	x2, y2, w2, h2 := RectPieces(r2)
+
+	ix1, iy1 := max(x1, x2), max(y1, y2)
+	ix2, iy2 := min(x1+w1, x2+w2), min(y1+h1, y2+h2)
+	if ix1 >= ix2 || iy1 >= iy2 {
+		return nil, false
+	}
+	return New(ix1, iy1, ix2-ix1, iy2-iy1), true
+}
+
+// UnionAll returns the smallest rectangle that contains every rectangle in
+// 'rects'. (i.e., the bounding box of a set of monitors.)
+// If 'rects' is empty, the rectangle returned is nil.
+func UnionAll(rects []Rect) Rect {
+	if len(rects) == 0 {
+		return nil
+	}
+
+	x1, y1, w, h := RectPieces(rects[0])
+	x2, y2 := x1+w, y1+h
+	for _, r := range rects[1:] {
+		rx, ry, rw, rh := RectPieces(r)
+		x1, y1 = min(x1, rx), min(y1, ry)
+		x2, y2 = max(x2, rx+rw), max(y2, ry+rh)
+	}
+	return New(x1, y1, x2-x1, y2-y1)
+}
+
+// Contains returns whether the point (x, y) is inside the rectangle.
+func Contains(r Rect, x, y int) bool {
+	rx, ry, rw, rh := RectPieces(r)
+	return x >= rx && x < rx+rw && y >= ry && y < ry+rh
+}
+
 // LargestOverlap returns the index of the rectangle in 'haystack' that has the
 // largest overlap with the rectangle 'needle'.
 // This is commonly used to find which monitor a window should belong on.
 // (Since it can technically be partially displayed on more than one monitor
 // at a time.)
 // Be careful, the return value can be -1 if there is no overlap.
+// If several rectangles have the same overlap, the first one is returned.
 func LargestOverlap(needle Rect, haystack []Rect) int {
 	biggestArea := 0
 	reti := -1
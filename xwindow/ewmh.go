@@ -39,7 +39,14 @@ func (w *Window) DecorGeometry() (xrect.Rect, error) {
 // adjust the width and height.
 // This should be used when moving/resizing top-level client windows with
 // reparenting window managers that support EWMH.
+// If the window manager doesn't support _NET_MOVERESIZE_WINDOW, then
+// MoveResize is used instead.
 func (w *Window) WMMoveResize(x, y, width, height int) error {
+	if !ewmh.IsSupported(w.X, "_NET_MOVERESIZE_WINDOW") {
+		w.MoveResize(x, y, width, height)
+		return nil
+	}
+
 	neww, newh, err := adjustSize(w.X, w.Id, width, height)
 	if err != nil {
 		return err
@@ -51,14 +58,27 @@ func (w *Window) WMMoveResize(x, y, width, height int) error {
 // WMMove changes the position of a window without touching the size.
 // This should be used when moving a top-level client window with
 // reparenting winow managers that support EWMH.
+// If the window manager doesn't support _NET_MOVERESIZE_WINDOW, then Move is
+// used instead.
 func (w *Window) WMMove(x, y int) error {
+	if !ewmh.IsSupported(w.X, "_NET_MOVERESIZE_WINDOW") {
+		w.Move(x, y)
+		return nil
+	}
 	return ewmh.MoveWindow(w.X, w.Id, x, y)
 }
 
 // WMResize changes the size of a window without touching the position.
 // This should be used when resizing a top-level client window with
 // reparenting window managers that support EWMH.
+// If the window manager doesn't support _NET_MOVERESIZE_WINDOW, then Resize
+// is used instead.
 func (w *Window) WMResize(width, height int) error {
+	if !ewmh.IsSupported(w.X, "_NET_MOVERESIZE_WINDOW") {
+		w.Resize(width, height)
+		return nil
+	}
+
 	neww, newh, err := adjustSize(w.X, w.Id, width, height)
 	if err != nil {
 		return err
@@ -85,7 +105,7 @@ func adjustSize(xu *xgbutil.XUtil, win xproto.Window,
 	}
 
 	// geometry with decorations
-	pGeom, err := RawGeometry(xu, xproto.Drawable(win))
+	pGeom, err := New(xu, win).DecorGeometry()
 	if err != nil {
 		return 0, 0, err
 	}
@@ -100,3 +120,20 @@ func adjustSize(xu *xgbutil.XUtil, win xproto.Window,
 	}
 	return neww, newh, nil
 }
+
+// Opacity returns the opacity of the window in _NET_WM_WINDOW_OPACITY, in the
+// range [0.0, 1.0], where 1.0 is completely opaque. An error is returned if
+// the property isn't set, in which case the window is completely opaque.
+// Compositing managers look for this property on top-level windows, so with
+// a reparenting window manager, it should be read from the frame of a client.
+// (See FrameId.)
+func (w *Window) Opacity() (float64, error) {
+	return ewmh.WmWindowOpacityGet(w.X, w.Id)
+}
+
+// OpacitySet sets _NET_WM_WINDOW_OPACITY on the window, which a compositing
+// manager uses to make the window translucent. The opacity is clamped to the
+// range [0.0, 1.0], where 0.0 is completely transparent.
+func (w *Window) OpacitySet(opacity float64) error {
+	return ewmh.WmWindowOpacitySet(w.X, w.Id, opacity)
+}
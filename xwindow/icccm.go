@@ -90,3 +90,88 @@ func (w *Window) WMTakeFocus(cb func(w *Window, tstamp xproto.Timestamp)) {
 			}
 		}).Connect(w.X, w.Id)
 }
+
+// ApplyHints constrains the size 'width' x 'height' to the WM_NORMAL_HINTS
+// in 'hints', as described in Section 4.1.2.3 of the ICCCM, and returns the
+// new size. Only the hints whose flags are set in hints.Flags are used.
+// Namely, the size is clamped to the minimum and maximum sizes, its aspect
+// ratio is kept between the minimum and maximum aspect ratios, and it is
+// snapped down to a whole number of resize increments above the base size.
+// As the ICCCM says, the base and minimum sizes stand in for each other when
+// only one of them is set, except that the minimum size is never subtracted
+// before checking the aspect ratio. If 'hints' is nil, the size is returned
+// unchanged.
+// This is useful for window managers when a client is resized, i.e., by the
+// user dragging the edge of its frame.
+func ApplyHints(hints *icccm.NormalHints, width, height int) (int, int) {
+	if hints == nil {
+		return width, height
+	}
+
+	var baseW, baseH, incW, incH, minW, minH, maxW, maxH int
+	if hints.Flags&icccm.SizeHintPBaseSize > 0 {
+		baseW, baseH = int(hints.BaseWidth), int(hints.BaseHeight)
+		incW, incH = baseW, baseH
+		minW, minH = baseW, baseH
+	}
+	if hints.Flags&icccm.SizeHintPMinSize > 0 {
+		minW, minH = int(hints.MinWidth), int(hints.MinHeight)
+		if hints.Flags&icccm.SizeHintPBaseSize == 0 {
+			incW, incH = minW, minH
+		}
+	}
+	if hints.Flags&icccm.SizeHintPMaxSize > 0 {
+		maxW, maxH = int(hints.MaxWidth), int(hints.MaxHeight)
+	}
+	width = clampHint(width, minW, maxW)
+	height = clampHint(height, minH, maxH)
+
+	// The aspect ratio applies to the size above the base size, if any.
+	w, h := width-baseW, height-baseH
+	if hints.Flags&icccm.SizeHintPAspect > 0 && w > 0 && h > 0 {
+		ratio := float64(w) / float64(h)
+		if hints.MaxAspectNum > 0 && hints.MaxAspectDen > 0 {
+			maxRatio := float64(hints.MaxAspectNum) /
+				float64(hints.MaxAspectDen)
+			if ratio > maxRatio {
+				w = int(float64(h)*maxRatio + 0.5)
+			}
+		}
+		if hints.MinAspectNum > 0 && hints.MinAspectDen > 0 {
+			minRatio := float64(hints.MinAspectNum) /
+				float64(hints.MinAspectDen)
+			if ratio < minRatio {
+				h = int(float64(w)/minRatio + 0.5)
+			}
+		}
+	}
+	width, height = w+baseW, h+baseH
+
+	// The increments apply to the size above the base (or minimum) size.
+	w, h = width-incW, height-incH
+	if hints.Flags&icccm.SizeHintPResizeInc > 0 {
+		if inc := int(hints.WidthInc); inc > 0 && w > 0 {
+			w -= w % inc
+		}
+		if inc := int(hints.HeightInc); inc > 0 && h > 0 {
+			h -= h % inc
+		}
+	}
+
+	return clampHint(w+incW, minW, maxW), clampHint(h+incH, minH, maxH)
+}
+
+// clampHint clamps 'n' to the range [low, high], where a 'high' of 0 means
+// there is no maximum. The result is always at least 1.
+func clampHint(n, low, high int) int {
+	if high > 0 && n > high {
+		n = high
+	}
+	if n < low {
+		n = low
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
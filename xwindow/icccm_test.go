@@ -0,0 +1,61 @@
+package xwindow_test
+
+import (
+	"testing"
+
+	"github.com/jezek/xgbutil/icccm"
+	"github.com/jezek/xgbutil/xwindow"
+)
+
+func TestApplyHints(t *testing.T) {
+	hints := func(set func(nh *icccm.NormalHints)) *icccm.NormalHints {
+		nh := &icccm.NormalHints{}
+		set(nh)
+		return nh
+	}
+	tests := []struct {
+		name          string
+		hints         *icccm.NormalHints
+		width, height int
+		wantW, wantH  int
+	}{
+		{"nil", nil, 123, 45, 123, 45},
+		{"no flags", &icccm.NormalHints{MinWidth: 500, WidthInc: 7},
+			123, 45, 123, 45},
+		{"min", hints(func(nh *icccm.NormalHints) {
+			nh.MinSizeSet(100, 50)
+		}), 50, 20, 100, 50},
+		{"max", hints(func(nh *icccm.NormalHints) {
+			nh.MaxSizeSet(300, 200)
+		}), 500, 500, 300, 200},
+		{"increments above base", hints(func(nh *icccm.NormalHints) {
+			nh.BaseSizeSet(10, 20)
+			nh.ResizeIncSet(7, 5)
+		}), 100, 100, 94, 100},
+		{"increments above min", hints(func(nh *icccm.NormalHints) {
+			nh.MinSizeSet(10, 20)
+			nh.ResizeIncSet(7, 5)
+		}), 100, 100, 94, 100},
+		{"max aspect", hints(func(nh *icccm.NormalHints) {
+			nh.AspectSet(1, 1, 1, 1)
+		}), 200, 100, 100, 100},
+		{"min aspect", hints(func(nh *icccm.NormalHints) {
+			nh.AspectSet(2, 1, 2, 1)
+		}), 100, 100, 100, 50},
+		{"aspect above base", hints(func(nh *icccm.NormalHints) {
+			nh.BaseSizeSet(50, 10)
+			nh.AspectSet(1, 1, 1, 1)
+		}), 200, 100, 140, 100},
+		{"aspect ignores min", hints(func(nh *icccm.NormalHints) {
+			nh.MinSizeSet(50, 10)
+			nh.AspectSet(1, 1, 1, 1)
+		}), 200, 100, 100, 100},
+	}
+	for _, test := range tests {
+		w, h := xwindow.ApplyHints(test.hints, test.width, test.height)
+		if w != test.wantW || h != test.wantH {
+			t.Errorf("%s: Expected %dx%d, but got %dx%d.",
+				test.name, test.wantW, test.wantH, w, h)
+		}
+	}
+}
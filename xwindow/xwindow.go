@@ -2,6 +2,8 @@ package xwindow
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/jezek/xgb/xproto"
 
@@ -21,6 +23,20 @@ type Window struct {
 	Id        xproto.Window
 	Geom      xrect.Rect
 	Destroyed bool
+
+	// geomChans are the channels returned by GeometryChan that are still
+	// open. They are closed by Detach. They are protected by geomLck.
+	geomChans []*geometryChan
+	geomLck   sync.Mutex
+}
+
+// geometryChan is a channel returned by GeometryChan, along with the
+// handlers that send to it and close it.
+type geometryChan struct {
+	ch        chan xrect.Rect
+	closed    bool
+	configure *xevent.Handle
+	destroy   *xevent.Handle
 }
 
 // New creates a new window value from a window id and an XUtil type.
@@ -67,6 +83,32 @@ func Create(xu *xgbutil.XUtil, parent xproto.Window) (*Window, error) {
 	return win, nil
 }
 
+// CreateInputOnly is a convenience constructor that generates a new window id
+// and creates an InputOnly window with the given geometry and event mask.
+// InputOnly windows are invisible and have no visual or colormap, but they
+// still receive input events, which makes them useful for catching events in
+// a region of the screen. (i.e., a hot spot on the edge of the screen.)
+// An error can be generated from Generate or the CreateWindow request.
+func CreateInputOnly(xu *xgbutil.XUtil, parent xproto.Window,
+	x, y, width, height int, eventMask uint32) (*Window, error) {
+
+	win, err := Generate(xu)
+	if err != nil {
+		return nil, err
+	}
+
+	err = xproto.CreateWindowChecked(xu.Conn(), 0, win.Id, parent,
+		int16(x), int16(y), uint16(width), uint16(height), 0,
+		xproto.WindowClassInputOnly, 0,
+		xproto.CwEventMask, []uint32{eventMask}).Check()
+	if err != nil {
+		return nil, fmt.Errorf("CreateInputOnly: Could not create window: %s",
+			err)
+	}
+
+	return win, nil
+}
+
 // Must panics if err is non-nil or if win is nil. Otherwise, win is returned.
 func Must(win *Window, err error) *Window {
 	if err != nil {
@@ -122,6 +164,67 @@ func (w *Window) CreateChecked(parent xproto.Window, x, y, width, height,
 		uint32(valueMask), valueList).Check()
 }
 
+// CreateCheckedVisual is the same as CreateChecked, except the visual and
+// depth of the window are given explicitly instead of using the defaults of
+// the screen. For example, this is needed to create a 32 bit window with an
+// alpha channel.
+// If 'visual' isn't the root visual, X requires that the window has its own
+// colormap and border pixel. If they aren't in 'valueMask', a new colormap is
+// created for the visual and the border pixel is set to 0. (The colormap is
+// freed when the connection to X is closed.)
+func (w *Window) CreateCheckedVisual(parent xproto.Window,
+	x, y, width, height int, visual xproto.Visualid, depth byte,
+	valueMask int, valueList ...uint32) error {
+
+	if visual != w.X.Screen().RootVisual {
+		if valueMask&xproto.CwBorderPixel == 0 {
+			valueMask, valueList = insertValue(valueMask, valueList,
+				xproto.CwBorderPixel, 0)
+		}
+		if valueMask&xproto.CwColormap == 0 {
+			cmap, err := xproto.NewColormapId(w.X.Conn())
+			if err != nil {
+				return err
+			}
+			err = xproto.CreateColormapChecked(w.X.Conn(),
+				xproto.ColormapAllocNone, cmap, w.X.RootWin(), visual).Check()
+			if err != nil {
+				return fmt.Errorf("CreateCheckedVisual: Could not create a "+
+					"colormap for visual %d: %s", visual, err)
+			}
+			valueMask, valueList = insertValue(valueMask, valueList,
+				xproto.CwColormap, uint32(cmap))
+		}
+	}
+
+	return xproto.CreateWindowChecked(w.X.Conn(),
+		depth, w.Id, parent,
+		int16(x), int16(y), uint16(width), uint16(height), 0,
+		xproto.WindowClassInputOutput, visual,
+		uint32(valueMask), valueList).Check()
+}
+
+// insertValue adds a value to a value mask and value list, such that the
+// value list stays in the same order as the bits in the value mask.
+// 'mask' must be a single bit that isn't already in 'valueMask'.
+func insertValue(valueMask int, valueList []uint32,
+	mask int, value uint32) (int, []uint32) {
+
+	// The value goes after every value whose mask is a lower bit.
+	i := 0
+	for bit := 1; bit < mask; bit <<= 1 {
+		if valueMask&bit > 0 {
+			i++
+		}
+	}
+
+	newList := make([]uint32, 0, len(valueList)+1)
+	newList = append(newList, valueList[:i]...)
+	newList = append(newList, value)
+	newList = append(newList, valueList[i:]...)
+	return valueMask | mask, newList
+}
+
 // Change issues a ChangeWindowAttributes request with the provided mask
 // and value list. Please see Window.Create for an example on how to use
 // the mask and value list.
@@ -155,6 +258,101 @@ func (w *Window) Geometry() (xrect.Rect, error) {
 	return geom, err
 }
 
+// GeometryChan returns a channel that receives the new geometry of the window
+// whenever it changes. (i.e., when the user resizes it.) StructureNotify
+// events are selected on the window in addition to any events already
+// selected by this client.
+// Only the most recent geometry is kept if the channel isn't read from, so
+// the main event loop is never blocked.
+// The channel is closed when the window is destroyed or detached with
+// Window.Detach. The main event loop must be running for the channel to
+// receive anything.
+func (w *Window) GeometryChan() (<-chan xrect.Rect, error) {
+	err := xevent.SelectInputAdd(w.X, w.Id, xproto.EventMaskStructureNotify)
+	if err != nil {
+		return nil, err
+	}
+
+	gc := &geometryChan{ch: make(chan xrect.Rect, 1)}
+	gc.configure = xevent.ConnectHandle(w.X, xevent.ConfigureNotify, w.Id,
+		xevent.ConfigureNotifyFun(
+			func(xu *xgbutil.XUtil, ev xevent.ConfigureNotifyEvent) {
+				w.sendGeometry(gc, xrect.New(int(ev.X), int(ev.Y),
+					int(ev.Width), int(ev.Height)))
+			}))
+	gc.destroy = xevent.ConnectHandle(w.X, xevent.DestroyNotify, w.Id,
+		xevent.DestroyNotifyFun(
+			func(xu *xgbutil.XUtil, ev xevent.DestroyNotifyEvent) {
+				w.closeGeometryChans()
+			}))
+
+	w.geomLck.Lock()
+	w.geomChans = append(w.geomChans, gc)
+	w.geomLck.Unlock()
+	return gc.ch, nil
+}
+
+// sendGeometry sends a new geometry to a channel returned by GeometryChan,
+// unless the channel has already been closed.
+func (w *Window) sendGeometry(gc *geometryChan, geom xrect.Rect) {
+	w.geomLck.Lock()
+	defer w.geomLck.Unlock()
+
+	if gc.closed {
+		return
+	}
+
+	// Replace a geometry that hasn't been read yet.
+	select {
+	case <-gc.ch:
+	default:
+	}
+	gc.ch <- geom
+}
+
+// closeGeometryChans closes every channel returned by GeometryChan, and
+// removes the handlers that send to them.
+func (w *Window) closeGeometryChans() {
+	w.geomLck.Lock()
+	defer w.geomLck.Unlock()
+
+	for _, gc := range w.geomChans {
+		xevent.DetachHandle(w.X, gc.configure)
+		xevent.DetachHandle(w.X, gc.destroy)
+		gc.closed = true
+		close(gc.ch)
+	}
+	w.geomChans = nil
+}
+
+// DestroyNotifyFun runs 'cb' when the window is destroyed. StructureNotify
+// events are selected on the window in addition to any events already
+// selected by this client.
+// After 'cb' has run, the window is marked as destroyed and all of its event
+// handlers are removed with xevent.DetachWindow (which also removes its key
+// and mouse bindings). Any channels returned by GeometryChan are closed.
+// The main event loop must be running for 'cb' to be called.
+func (w *Window) DestroyNotifyFun(cb func()) error {
+	err := xevent.SelectInputAdd(w.X, w.Id, xproto.EventMaskStructureNotify)
+	if err != nil {
+		return err
+	}
+
+	xevent.DestroyNotifyFun(
+		func(xu *xgbutil.XUtil, ev xevent.DestroyNotifyEvent) {
+			// The parent may also report the destruction of this window if
+			// it selects SubstructureNotify.
+			if ev.Window != w.Id || w.Destroyed {
+				return
+			}
+			w.Destroyed = true
+			cb()
+			xevent.DetachWindow(w.X, w.Id)
+			w.closeGeometryChans()
+		}).Connect(w.X, w.Id)
+	return nil
+}
+
 // RawGeometry isn't smart. It just queries the window given for geometry.
 func RawGeometry(xu *xgbutil.XUtil, win xproto.Drawable) (xrect.Rect, error) {
 	xgeom, err := xproto.GetGeometry(xu.Conn(), win).Reply()
@@ -297,6 +495,30 @@ func (w *Window) StackSibling(sibling xproto.Window, mode byte) {
 		[]uint32{uint32(sibling), uint32(mode)})
 }
 
+// RaiseAbove puts the window directly above 'sibling' in the stacking order.
+// The same restrictions as StackSibling apply.
+func (w *Window) RaiseAbove(sibling xproto.Window) {
+	w.StackSibling(sibling, xproto.StackModeAbove)
+}
+
+// LowerBelow puts the window directly below 'sibling' in the stacking order.
+// The same restrictions as StackSibling apply.
+func (w *Window) LowerBelow(sibling xproto.Window) {
+	w.StackSibling(sibling, xproto.StackModeBelow)
+}
+
+// RaiseToTop puts the window at the top of the stacking order among its
+// siblings.
+func (w *Window) RaiseToTop() {
+	w.Stack(xproto.StackModeAbove)
+}
+
+// LowerToBottom puts the window at the bottom of the stacking order among its
+// siblings.
+func (w *Window) LowerToBottom() {
+	w.Stack(xproto.StackModeBelow)
+}
+
 // Map is a simple alias to map the window.
 func (w *Window) Map() {
 	if w == nil {
@@ -306,6 +528,37 @@ func (w *Window) Map() {
 	xproto.MapWindow(w.X.Conn(), w.Id)
 }
 
+// WaitForMapped blocks until the window is viewable, or until 'timeout' has
+// passed, in which case an error is returned. This is useful after mapping a
+// window, since some requests (like grabs) fail on windows that aren't
+// viewable yet.
+// Note that a window is only viewable if all of its ancestors are mapped too.
+// The window's map state is polled, so this works regardless of whether the
+// main event loop is running.
+func WaitForMapped(xu *xgbutil.XUtil, win xproto.Window,
+	timeout time.Duration) error {
+
+	deadline := time.Now().Add(timeout)
+	for {
+		attrs, err := xproto.GetWindowAttributes(xu.Conn(), win).Reply()
+		if err != nil {
+			return fmt.Errorf("WaitForMapped: Could not get attributes of "+
+				"window %x: %s", win, err)
+		}
+		if attrs.MapState == xproto.MapStateViewable {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("WaitForMapped: Window %x was not viewable "+
+				"after %s.", win, timeout)
+		}
+		time.Sleep(mapPollInterval)
+	}
+}
+
+// mapPollInterval is how often WaitForMapped checks the map state of a window.
+const mapPollInterval = 10 * time.Millisecond
+
 // Unmap is a simple alias to unmap the window.
 func (w *Window) Unmap() {
 	if w == nil {
@@ -332,28 +585,35 @@ func (w *Window) Destroy() {
 
 // Detach will detach this window's event handlers from all xevent, keybind
 // and mousebind callbacks.
+// Any channels returned by GeometryChan are closed.
 func (w *Window) Detach() {
 	keybind.Detach(w.X, w.Id)
 	mousebind.Detach(w.X, w.Id)
 	xevent.Detach(w.X, w.Id)
+	w.closeGeometryChans()
 }
 
 // Focus tries to issue a SetInputFocus to get the focus.
 // If you're trying to change the top-level active window, please use
 // ewmh.ActiveWindowReq instead.
 func (w *Window) Focus() {
-	mode := byte(xproto.InputFocusPointerRoot)
-	err := xproto.SetInputFocusChecked(w.X.Conn(), mode, w.Id, 0).Check()
-	if err != nil {
-		xgbutil.Logger.Println(err)
-	}
+	w.FocusTime(0, xproto.InputFocusPointerRoot)
 }
 
 // FocusParent is just like Focus, except it sets the "revert-to" mode to
 // Parent. This should be used when setting focus to a sub-window.
 func (w *Window) FocusParent(tstamp xproto.Timestamp) {
-	mode := byte(xproto.InputFocusParent)
-	err := xproto.SetInputFocusChecked(w.X.Conn(), mode, w.Id, tstamp).Check()
+	w.FocusTime(tstamp, xproto.InputFocusParent)
+}
+
+// FocusTime is just like Focus, except the timestamp and "revert-to" mode
+// are given explicitly. 'tstamp' should be the time of the event that caused
+// the focus change, since ICCCM discourages the use of CurrentTime (0).
+// 'revertTo' values can be found as constants in xgb/xproto with the prefix
+// InputFocus.
+func (w *Window) FocusTime(tstamp xproto.Timestamp, revertTo byte) {
+	err := xproto.SetInputFocusChecked(w.X.Conn(), revertTo, w.Id,
+		tstamp).Check()
 	if err != nil {
 		xgbutil.Logger.Println(err)
 	}
@@ -391,3 +651,55 @@ func (w *Window) Parent() (*Window, error) {
 	}
 	return New(w.X, tree.Parent), nil
 }
+
+// FrameId returns the id of the frame window that a reparenting window
+// manager has put around this window. This is found by walking up the window
+// tree until the parent is the root window, and returning the last window
+// seen before the root.
+// If the window isn't reparented, or is an override-redirect window (which
+// window managers leave alone), the window's own id is returned.
+func (w *Window) FrameId() (xproto.Window, error) {
+	attrs, err := xproto.GetWindowAttributes(w.X.Conn(), w.Id).Reply()
+	if err != nil {
+		return 0, fmt.Errorf("FrameId: Could not get attributes of window "+
+			"%x: %s", w.Id, err)
+	}
+	if attrs.OverrideRedirect {
+		return w.Id, nil
+	}
+
+	frame := w.Id
+	for {
+		tree, err := xproto.QueryTree(w.X.Conn(), frame).Reply()
+		if err != nil {
+			return 0, fmt.Errorf("FrameId: Error retrieving parent window "+
+				"for %x: %s", frame, err)
+		}
+		if tree.Parent == 0 || tree.Parent == w.X.RootWin() {
+			return frame, nil
+		}
+		frame = tree.Parent
+	}
+}
+
+// TranslateFrom converts the point (x, y), relative to the origin of the
+// window 'src', to a point relative to the origin of this window. (i.e., pass
+// the root window as 'src' to convert root coordinates.) The child of this
+// window that contains the point is also returned, or 0 if the point isn't
+// in any child window.
+// An error is returned if the two windows are on different screens.
+func (w *Window) TranslateFrom(src xproto.Window,
+	x, y int) (int, int, xproto.Window, error) {
+
+	reply, err := xproto.TranslateCoordinates(w.X.Conn(), src, w.Id,
+		int16(x), int16(y)).Reply()
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("TranslateFrom: Could not translate "+
+			"(%d, %d) from window %x to window %x: %s", x, y, src, w.Id, err)
+	}
+	if !reply.SameScreen {
+		return 0, 0, 0, fmt.Errorf("TranslateFrom: Windows %x and %x are "+
+			"not on the same screen.", src, w.Id)
+	}
+	return int(reply.DstX), int(reply.DstY), reply.Child, nil
+}